@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const alertStateFile = "alert_state.json"
+
+// AlertRule is a single configurable threshold. Supported types:
+//
+//	budget_used           fires when more than Threshold percent of the budget is spent
+//	category_over_target  fires when Category (root or leaf name) spends more than Target
+//	transaction_above     fires for every outgoing transaction larger than Threshold
+//	remaining_below       fires when the remaining budget drops below Threshold
+//	anomaly               fires for every detected anomaly, or only those of
+//	                      Kind (merchant_outlier, category_spike,
+//	                      duplicate_charge or new_payee)
+type AlertRule struct {
+	Type      string  `json:"type"`
+	Threshold float64 `json:"threshold"`
+	Category  string  `json:"category,omitempty"`
+	Target    float64 `json:"target,omitempty"`
+	Kind      string  `json:"kind,omitempty"`
+}
+
+// AlertConfig is the contents of the alerts file
+type AlertConfig struct {
+	Rules []AlertRule `json:"rules"`
+}
+
+// defaultAlertConfig is used when no alerts file exists
+var defaultAlertConfig = AlertConfig{
+	Rules: []AlertRule{
+		{Type: "budget_used", Threshold: 80},
+		{Type: "remaining_below", Threshold: 0},
+	},
+}
+
+// Alert is a rule that fired. The key identifies it across runs, so an alert
+// that keeps firing is only sent once.
+type Alert struct {
+	Key        string
+	Message    string
+	Escalation bool // something went over budget
+}
+
+// AlertInput holds the figures the rules are evaluated against. Amounts
+// follow the booking signs: spending is negative.
+type AlertInput struct {
+	Period         string
+	FamilyBudget   float64
+	FamilySpending float64
+	Remaining      float64
+	RootTotals     map[string]float64
+	LeafTotals     map[string]float64
+	Mutations      []FinancialMutation
+	Anomalies      []Anomaly
+}
+
+// loadAlertConfig reads the alerts file, falling back to the defaults if it doesn't exist
+func loadAlertConfig(filename string) (AlertConfig, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return defaultAlertConfig, nil
+	}
+	if err != nil {
+		return AlertConfig{}, fmt.Errorf("reading alerts file: %w", err)
+	}
+
+	var config AlertConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return AlertConfig{}, fmt.Errorf("unmarshaling alerts file: %w", err)
+	}
+	for i, rule := range config.Rules {
+		if err := rule.validate(); err != nil {
+			return AlertConfig{}, fmt.Errorf("alert rule %d: %w", i+1, err)
+		}
+	}
+	return config, nil
+}
+
+// validate rejects rules that evaluateAlerts wouldn't know how to evaluate
+func (r AlertRule) validate() error {
+	switch r.Type {
+	case "budget_used", "transaction_above", "remaining_below":
+		return nil
+	case "anomaly":
+		if r.Category != "" {
+			return fmt.Errorf("anomaly rules take the anomaly kind as \"kind\", not \"category\"")
+		}
+		switch r.Kind {
+		case "", anomalyMerchantOutlier, anomalyCategorySpike, anomalyDuplicateCharge, anomalyNewPayee:
+			return nil
+		}
+		return fmt.Errorf("unknown anomaly kind %q", r.Kind)
+	case "category_over_target":
+		if r.Category == "" {
+			return fmt.Errorf("category_over_target needs a category")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+}
+
+// evaluateAlerts returns every alert that currently fires
+func evaluateAlerts(config AlertConfig, input AlertInput) []Alert {
+	var alerts []Alert
+
+	for _, rule := range config.Rules {
+		switch rule.Type {
+		case "budget_used":
+			if input.FamilyBudget <= 0 {
+				continue
+			}
+			used := -input.FamilySpending / input.FamilyBudget * 100
+			if used > rule.Threshold {
+				alerts = append(alerts, Alert{
+					Key:        fmt.Sprintf("budget_used:%g:%s", rule.Threshold, input.Period),
+					Message:    fmt.Sprintf("Budget used is %.1f%% (above %g%%)", used, rule.Threshold),
+					Escalation: rule.Threshold >= 100,
+				})
+			}
+
+		case "category_over_target":
+			total, ok := input.RootTotals[rule.Category]
+			if !ok {
+				total, ok = input.LeafTotals[rule.Category]
+			}
+			if ok && -total > rule.Target {
+				alerts = append(alerts, Alert{
+					Key:        fmt.Sprintf("category_over_target:%s:%s", rule.Category, input.Period),
+					Message:    fmt.Sprintf("%s spent €%.2f of its €%.2f target", rule.Category, -total, rule.Target),
+					Escalation: true,
+				})
+			}
+
+		case "transaction_above":
+			for _, mut := range input.Mutations {
+				amount, err := strconv.ParseFloat(mut.Amount, 64)
+				if err != nil || -amount <= rule.Threshold {
+					continue
+				}
+				alerts = append(alerts, Alert{
+					Key:     fmt.Sprintf("transaction_above:%g:%s", rule.Threshold, mut.ID),
+					Message: fmt.Sprintf("Large transaction on %s: €%.2f to %s", mut.Date, -amount, mut.ContraAccountName),
+				})
+			}
+
+		case "remaining_below":
+			if input.Remaining < rule.Threshold {
+				alerts = append(alerts, Alert{
+					Key:        fmt.Sprintf("remaining_below:%g:%s", rule.Threshold, input.Period),
+					Message:    fmt.Sprintf("Remaining budget is €%.2f (below €%.2f)", input.Remaining, rule.Threshold),
+					Escalation: rule.Threshold <= 0,
+				})
+			}
+
+		case "anomaly":
+			for _, anomaly := range input.Anomalies {
+				if rule.Kind != "" && rule.Kind != anomaly.Kind {
+					continue
+				}
+				alerts = append(alerts, Alert{
+					Key:     "anomaly:" + anomaly.Key,
+					Message: anomaly.Message,
+				})
+			}
+		}
+	}
+
+	return alerts
+}
+
+// alertMessages returns the messages of the alerts, as carried by a Report
+func alertMessages(alerts []Alert) []string {
+	var messages []string
+	for _, alert := range alerts {
+		messages = append(messages, alert.Message)
+	}
+	return messages
+}
+
+// alertState records the alerts that were sent and are still firing
+type alertState struct {
+	Firing map[string]time.Time `json:"firing"`
+}
+
+// loadAlertState reads the state file, returning an empty state if it doesn't exist yet
+func loadAlertState(filename string) (*alertState, error) {
+	state := &alertState{Firing: make(map[string]time.Time)}
+
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("unmarshaling state: %w", err)
+	}
+	if state.Firing == nil {
+		state.Firing = make(map[string]time.Time)
+	}
+
+	return state, nil
+}
+
+// update returns the current alerts that weren't sent before. Alerts that
+// stopped firing are forgotten, so they are sent again if they return. New
+// alerts only count as firing once markSent records their delivery.
+func (s *alertState) update(alerts []Alert) []Alert {
+	var newAlerts []Alert
+	firing := make(map[string]time.Time)
+
+	for _, alert := range alerts {
+		if since, ok := s.Firing[alert.Key]; ok {
+			firing[alert.Key] = since
+			continue
+		}
+		newAlerts = append(newAlerts, alert)
+	}
+
+	s.Firing = firing
+	sort.SliceStable(newAlerts, func(i, j int) bool {
+		return newAlerts[i].Escalation && !newAlerts[j].Escalation
+	})
+	return newAlerts
+}
+
+// markSent records alerts that reached at least one channel
+func (s *alertState) markSent(alerts []Alert) {
+	for _, alert := range alerts {
+		if _, ok := s.Firing[alert.Key]; !ok {
+			s.Firing[alert.Key] = time.Now()
+		}
+	}
+}
+
+// save writes the state file
+func (s *alertState) save(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling state: %w", err)
+	}
+	return os.WriteFile(filename, data, 0644)
+}
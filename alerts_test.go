@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlertStateOnlyRemembersSentAlerts(t *testing.T) {
+	state := &alertState{Firing: make(map[string]time.Time)}
+	alerts := []Alert{{Key: "a", Message: "A"}, {Key: "b", Message: "B"}}
+
+	if got := state.update(alerts); len(got) != 2 {
+		t.Fatalf("first run: %d new alerts, want 2", len(got))
+	}
+	// Nothing was delivered, so both are new again
+	if got := state.update(alerts); len(got) != 2 {
+		t.Fatalf("after failed delivery: %d new alerts, want 2", len(got))
+	}
+
+	state.markSent(alerts[:1])
+	got := state.update(alerts)
+	if len(got) != 1 || got[0].Key != "b" {
+		t.Fatalf("after delivering a: new alerts %v, want only b", got)
+	}
+
+	// a stops firing and is forgotten
+	state.update(nil)
+	if len(state.Firing) != 0 {
+		t.Fatalf("firing = %v, want empty", state.Firing)
+	}
+}
+
+func TestLoadAlertConfigRejectsUnknownTypes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "alerts.json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	_, err := loadAlertConfig(write(`{"rules": [{"type": "budget_used", "threshold": 80}, {"type": "budget_usde"}]}`))
+	if err == nil || !strings.Contains(err.Error(), "budget_usde") {
+		t.Fatalf("error = %v, want the unknown type", err)
+	}
+
+	if _, err := loadAlertConfig(write(`{"rules": [{"type": "budget_used", "threshold": 80}]}`)); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+}
+
+func TestLoadAlertConfigAnomalyKinds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	os.WriteFile(path, []byte(`{"rules": [{"type": "anomaly", "kind": "duplicate_charge"}, {"type": "anomaly", "kind": "duplicate"}]}`), 0644)
+	if _, err := loadAlertConfig(path); err == nil || !strings.Contains(err.Error(), `"duplicate"`) {
+		t.Fatalf("error = %v, want the unknown kind", err)
+	}
+
+	config := AlertConfig{Rules: []AlertRule{{Type: "anomaly", Kind: anomalyNewPayee}}}
+	alerts := evaluateAlerts(config, AlertInput{Anomalies: []Anomaly{
+		{Kind: anomalyDuplicateCharge, Key: "dup", Message: "Duplicate"},
+		{Kind: anomalyNewPayee, Key: "new", Message: "New payee"},
+	}})
+	if len(alerts) != 1 || alerts[0].Message != "New payee" {
+		t.Errorf("alerts = %v, want only the new payee", alerts)
+	}
+}
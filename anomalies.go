@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// outlierFactor is how far above a merchant's usual charges a transaction
+	// has to be; merchants need outlierMinHistory earlier charges
+	outlierFactor     = 2.0
+	outlierMinHistory = 3
+
+	// A category is flagged when it spends spikeFactor times its baseline, the
+	// average of the previous baselineMonths months, and at least spikeMinAmount more
+	spikeFactor    = 1.5
+	spikeMinAmount = 50.0
+	baselineMonths = 3
+
+	// duplicateWindowDays is how close two identical charges have to be
+	duplicateWindowDays = 3
+)
+
+// Anomaly kinds, also used to select anomalies in alert rules
+const (
+	anomalyMerchantOutlier = "merchant_outlier"
+	anomalyCategorySpike   = "category_spike"
+	anomalyDuplicateCharge = "duplicate_charge"
+	anomalyNewPayee        = "new_payee"
+)
+
+// Anomaly is something unusual in the period's transactions. The key is stable
+// across runs so it's only alerted once.
+type Anomaly struct {
+	Key     string
+	Kind    string
+	Message string
+}
+
+// AnomalyInput is what detection looks at: the period's mutations, the
+// history before it and the documents and accounts to total categories with
+type AnomalyInput struct {
+	PeriodStart time.Time
+	Mutations   []FinancialMutation
+	History     []FinancialMutation // may include the period itself, it is skipped
+	Documents   map[string]Document
+	TaxRates    []TaxRate
+	Accounts    []LedgerAccount
+}
+
+// detectAnomalies runs every check and returns the anomalies ordered by kind
+func detectAnomalies(input AnomalyInput) []Anomaly {
+	periodStart := input.PeriodStart.Format("2006-01-02")
+	var prior []FinancialMutation
+	for _, mut := range input.History {
+		if mut.Date < periodStart {
+			prior = append(prior, mut)
+		}
+	}
+
+	var anomalies []Anomaly
+	anomalies = append(anomalies, merchantOutliers(input.Mutations, prior)...)
+	anomalies = append(anomalies, categorySpikes(input, prior)...)
+	anomalies = append(anomalies, duplicateCharges(input.Mutations, prior)...)
+	anomalies = append(anomalies, newPayees(input.Mutations, prior)...)
+	return anomalies
+}
+
+// outgoing returns the amount spent by a mutation, and false for incoming money
+func outgoing(mut FinancialMutation) (float64, bool) {
+	var amount float64
+	fmt.Sscanf(mut.Amount, "%f", &amount)
+	return -amount, amount < 0
+}
+
+// merchantOutliers flags charges far above what a merchant usually charges
+func merchantOutliers(mutations, prior []FinancialMutation) []Anomaly {
+	usual := make(map[string][]float64)
+	for _, mut := range prior {
+		if amount, ok := outgoing(mut); ok && recurringKey(mut) != "" {
+			usual[recurringKey(mut)] = append(usual[recurringKey(mut)], amount)
+		}
+	}
+
+	var anomalies []Anomaly
+	for _, mut := range mutations {
+		amount, ok := outgoing(mut)
+		amounts := usual[recurringKey(mut)]
+		if !ok || len(amounts) < outlierMinHistory {
+			continue
+		}
+
+		sorted := append([]float64(nil), amounts...)
+		sort.Float64s(sorted)
+		median := sorted[len(sorted)/2]
+		if amount <= sorted[len(sorted)-1] || amount <= median*outlierFactor {
+			continue
+		}
+
+		anomalies = append(anomalies, Anomaly{
+			Key:  fmt.Sprintf("%s:%s", anomalyMerchantOutlier, mut.ID),
+			Kind: anomalyMerchantOutlier,
+			Message: fmt.Sprintf("Unusually large charge on %s: €%.2f to %s (usually €%.2f to €%.2f)",
+				mut.Date, amount, mut.ContraAccountName, sorted[0], sorted[len(sorted)-1]),
+		})
+	}
+	return anomalies
+}
+
+// categorySpikes flags categories spending well above their average of the
+// previous months
+func categorySpikes(input AnomalyInput, prior []FinancialMutation) []Anomaly {
+	accountMap := make(map[string]LedgerAccount)
+	for _, acc := range input.Accounts {
+		accountMap[acc.ID] = acc
+	}
+
+	byMonth := make(map[string][]FinancialMutation)
+	earliest := ""
+	for _, mut := range prior {
+		if len(mut.Date) < 7 {
+			continue
+		}
+		byMonth[mut.Date[:7]] = append(byMonth[mut.Date[:7]], mut)
+		if earliest == "" || mut.Date[:7] < earliest {
+			earliest = mut.Date[:7]
+		}
+	}
+
+	// Months without transactions count as zero, but the history has to reach
+	// back far enough to have a baseline at all
+	baseline := make(map[string]float64)
+	var months int
+	for i := 1; i <= baselineMonths; i++ {
+		month := input.PeriodStart.AddDate(0, -i, 0).Format("2006-01")
+		if earliest == "" || earliest > month {
+			break
+		}
+		months++
+		totals, _, _ := aggregateTotals(&Snapshot{Mutations: byMonth[month], Documents: input.Documents, TaxRates: input.TaxRates}, input.Accounts)
+		for id, total := range totals {
+			baseline[id] += total
+		}
+	}
+	if months == 0 {
+		return nil
+	}
+
+	current, _, _ := aggregateTotals(&Snapshot{Mutations: input.Mutations, Documents: input.Documents, TaxRates: input.TaxRates}, input.Accounts)
+
+	var anomalies []Anomaly
+	for id, total := range current {
+		acc, ok := accountMap[id]
+		if !ok || (acc.AccountType != "equity" && acc.AccountType != "expenses") {
+			continue
+		}
+		spent := -total
+		average := -baseline[id] / float64(months)
+		if spent < average*spikeFactor || spent-average < spikeMinAmount {
+			continue
+		}
+		message := fmt.Sprintf("%s is at €%.2f, well above its %d-month average of €%.2f", acc.Name, spent, months, average)
+		if average < 0.005 {
+			message = fmt.Sprintf("%s is at €%.2f, with no spending in the previous %d months", acc.Name, spent, months)
+		}
+		anomalies = append(anomalies, Anomaly{
+			Key:     fmt.Sprintf("%s:%s:%s", anomalyCategorySpike, acc.Name, input.PeriodStart.Format("2006-01")),
+			Kind:    anomalyCategorySpike,
+			Message: message,
+		})
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].Key < anomalies[j].Key
+	})
+	return anomalies
+}
+
+// duplicateCharges flags a charge when the same counterparty charged the same
+// amount within a few days before it
+func duplicateCharges(mutations, prior []FinancialMutation) []Anomaly {
+	inPeriod := make(map[string]bool)
+	for _, mut := range mutations {
+		inPeriod[mut.ID] = true
+	}
+
+	candidates := append([]FinancialMutation(nil), mutations...)
+	for _, mut := range prior {
+		if !inPeriod[mut.ID] {
+			candidates = append(candidates, mut)
+		}
+	}
+	sortMutations(candidates)
+
+	var anomalies []Anomaly
+	for i, mut := range candidates {
+		amount, ok := outgoing(mut)
+		key := recurringKey(mut)
+		if !ok || key == "" || !inPeriod[mut.ID] {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", mut.Date)
+		if err != nil {
+			continue
+		}
+
+		for j := i - 1; j >= 0; j-- {
+			earlier := candidates[j]
+			earlierDate, err := time.Parse("2006-01-02", earlier.Date)
+			if err != nil || date.Sub(earlierDate) > duplicateWindowDays*24*time.Hour {
+				break
+			}
+			earlierAmount, ok := outgoing(earlier)
+			if ok && recurringKey(earlier) == key && math.Abs(earlierAmount-amount) < 0.005 {
+				anomalies = append(anomalies, Anomaly{
+					Key:  fmt.Sprintf("%s:%s", anomalyDuplicateCharge, mut.ID),
+					Kind: anomalyDuplicateCharge,
+					Message: fmt.Sprintf("Possible duplicate charge: €%.2f to %s on %s and %s",
+						amount, mut.ContraAccountName, earlier.Date, mut.Date),
+				})
+				break
+			}
+		}
+	}
+	return anomalies
+}
+
+// newPayees flags the first payment to a counterparty that doesn't appear in
+// the history. Without any history every payee would be new, so nothing is flagged.
+func newPayees(mutations, prior []FinancialMutation) []Anomaly {
+	if len(prior) == 0 {
+		return nil
+	}
+	known := make(map[string]bool)
+	for _, mut := range prior {
+		known[recurringKey(mut)] = true
+	}
+
+	var anomalies []Anomaly
+	for _, mut := range mutations {
+		amount, ok := outgoing(mut)
+		key := recurringKey(mut)
+		if !ok || key == "" || known[key] {
+			continue
+		}
+		known[key] = true
+		anomalies = append(anomalies, Anomaly{
+			Key:     fmt.Sprintf("%s:%s", anomalyNewPayee, key),
+			Kind:    anomalyNewPayee,
+			Message: fmt.Sprintf("New payee on %s: €%.2f to %s", mut.Date, amount, mut.ContraAccountName),
+		})
+	}
+	return anomalies
+}
+
+// printAnomalies writes the anomalies to the console
+func printAnomalies(anomalies []Anomaly) {
+	if len(anomalies) == 0 {
+		fmt.Println("   Nothing unusual")
+		return
+	}
+	for _, a := range anomalies {
+		fmt.Printf("   🔍 %s\n", a.Message)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDetectAnomalies(t *testing.T) {
+	food := LedgerAccount{ID: "food", Name: "Boodschappen", AccountType: "equity"}
+	charge := func(id, date, amount, name, account string) FinancialMutation {
+		mut := FinancialMutation{ID: id, Date: date, Amount: amount, ContraAccountName: name}
+		if account != "" {
+			mut.LedgerAccountBookings = []LedgerAccountBooking{{LedgerAccountID: account, Price: amount}}
+		}
+		return mut
+	}
+	history := []FinancialMutation{
+		charge("p1", "2026-01-10", "-30.00", "Jumbo", "food"),
+		charge("p2", "2026-02-10", "-35.00", "Jumbo", "food"),
+		charge("p3", "2026-03-10", "-40.00", "Jumbo", "food"),
+		charge("p4", "2026-03-05", "-12.99", "Netflix", ""),
+	}
+	april := []FinancialMutation{
+		charge("a1", "2026-04-03", "-150.00", "Jumbo", "food"),
+		charge("a2", "2026-04-05", "-12.99", "Netflix", ""),
+		charge("a3", "2026-04-06", "-12.99", "Netflix", ""),
+		charge("a4", "2026-04-07", "-80.00", "Fietsenwinkel", ""),
+	}
+
+	anomalies := detectAnomalies(AnomalyInput{
+		PeriodStart: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		Mutations:   april,
+		History:     append(history, april...),
+		Accounts:    []LedgerAccount{food},
+	})
+
+	var keys []string
+	for _, anomaly := range anomalies {
+		keys = append(keys, anomaly.Key)
+	}
+	want := []string{
+		"merchant_outlier:a1",
+		"category_spike:Boodschappen:2026-04",
+		"duplicate_charge:a3",
+		"new_payee:name:fietsenwinkel",
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("anomalies = %v, want %v", keys, want)
+	}
+
+	// Without history nothing stands out
+	if anomalies := detectAnomalies(AnomalyInput{
+		PeriodStart: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		Mutations:   april[:1],
+		History:     april[:1],
+		Accounts:    []LedgerAccount{food},
+	}); len(anomalies) != 0 {
+		t.Errorf("anomalies without history = %v, want none", anomalies)
+	}
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBalancesFile = "balances.json"
+
+	// defaultLargeTransaction is the amount from which transactions are itemized
+	defaultLargeTransaction = 500.0
+
+	categoryUncategorized     = "Uncategorized"
+	categoryInternalTransfers = "Internal transfers"
+)
+
+// FinancialAccount is a bank account, credit card or other account that
+// financial mutations are imported into
+type FinancialAccount struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"` // e.g. FinancialAccount::BankAccount
+	Name       string    `json:"name"`
+	Identifier string    `json:"identifier"` // the IBAN for bank accounts
+	Currency   string    `json:"currency"`
+	Provider   string    `json:"provider"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// kind returns the account type without its prefix, e.g. "BankAccount"
+func (a FinancialAccount) kind() string {
+	return strings.TrimPrefix(a.Type, "FinancialAccount::")
+}
+
+// GetFinancialAccounts fetches the administration's financial accounts
+func (c *Client) GetFinancialAccounts() ([]FinancialAccount, error) {
+	body, err := c.doRequest("GET", "financial_accounts.json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []FinancialAccount
+	if err := json.Unmarshal(body, &accounts); err != nil {
+		return nil, fmt.Errorf("unmarshaling response: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// FinancialStatement is an imported bank statement. Its official balance is
+// the account's balance at the end of its official date.
+type FinancialStatement struct {
+	ID                 string `json:"id"`
+	FinancialAccountID string `json:"financial_account_id"`
+	Reference          string `json:"reference"`
+	OfficialDate       string `json:"official_date"`
+	OfficialBalance    string `json:"official_balance"`
+}
+
+// GetFinancialStatement fetches a single financial statement. The API
+// documents creating, updating and deleting statements; reading one back isn't
+// documented, so callers have to cope with it failing.
+func (c *Client) GetFinancialStatement(id string) (*FinancialStatement, error) {
+	body, err := c.doRequest("GET", fmt.Sprintf("financial_statements/%s.json", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var statement FinancialStatement
+	if err := json.Unmarshal(body, &statement); err != nil {
+		return nil, fmt.Errorf("unmarshaling response: %w", err)
+	}
+
+	return &statement, nil
+}
+
+// KnownBalance is an account's balance at the end of a day. Balances are
+// derived from a known balance and the stored mutations before or after it.
+// The official balance of the latest bank statement is the known balance;
+// balances.json gives one for accounts without statements.
+type KnownBalance struct {
+	Date        string  `json:"date"`
+	Balance     float64 `json:"balance"`
+	StatementID string  `json:"statement_id,omitempty"` // set for statement balances
+}
+
+// syncStatementBalances stores the official balance of the latest statement
+// of every financial account, found through the stored mutations. Statements
+// that are already stored aren't fetched again. It returns the number of
+// balances updated. It stops at the first statement that can't be fetched,
+// leaving the stored balances as they are.
+func syncStatementBalances(client *Client, store *Store) (int, error) {
+	latest := make(map[string]FinancialMutation) // financial account ID → latest mutation with a statement
+	for _, mut := range store.FinancialMutations {
+		if mut.FinancialStatementID == "" {
+			continue
+		}
+		if current, ok := latest[mut.FinancialAccountID]; !ok || mut.Date > current.Date {
+			latest[mut.FinancialAccountID] = mut
+		}
+	}
+
+	var updated int
+	for accountID, mut := range latest {
+		if store.Balances[accountID].StatementID == mut.FinancialStatementID {
+			continue
+		}
+		statement, err := client.GetFinancialStatement(mut.FinancialStatementID)
+		if err != nil {
+			return updated, fmt.Errorf("fetching statement %s: %w", mut.FinancialStatementID, err)
+		}
+		var balance float64
+		if _, err := fmt.Sscanf(statement.OfficialBalance, "%f", &balance); err != nil || statement.OfficialDate == "" {
+			// Statements entered by hand don't always have an official balance
+			continue
+		}
+		store.Balances[accountID] = KnownBalance{Date: statement.OfficialDate, Balance: balance, StatementID: statement.ID}
+		updated++
+	}
+	return updated, nil
+}
+
+// balanceAnchors returns the known balance of every stored financial account
+// by ID: its statement balance, or else its entry in balances.json
+func balanceAnchors(store *Store, balances map[string]KnownBalance) map[string]KnownBalance {
+	anchors := make(map[string]KnownBalance)
+	for _, account := range store.FinancialAccounts {
+		if anchor, ok := store.Balances[account.ID]; ok {
+			anchors[account.ID] = anchor
+		} else if anchor, ok := knownBalance(account, balances); ok {
+			anchors[account.ID] = anchor
+		}
+	}
+	return anchors
+}
+
+// loadKnownBalances reads the known balances by financial account ID, name or
+// IBAN. A missing file means no balances are known.
+func loadKnownBalances(filename string) (map[string]KnownBalance, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading balances: %w", err)
+	}
+
+	var balances map[string]KnownBalance
+	if err := json.Unmarshal(data, &balances); err != nil {
+		return nil, fmt.Errorf("unmarshaling balances: %w", err)
+	}
+	for key, balance := range balances {
+		if _, err := time.Parse("2006-01-02", balance.Date); err != nil {
+			return nil, fmt.Errorf("balance of %s: invalid date %q", key, balance.Date)
+		}
+	}
+	return balances, nil
+}
+
+// knownBalance finds the known balance of an account by ID, name or IBAN
+func knownBalance(account FinancialAccount, balances map[string]KnownBalance) (KnownBalance, bool) {
+	for key, balance := range balances {
+		if key == account.ID || strings.EqualFold(key, account.Name) ||
+			(account.Identifier != "" && normalizeIBAN(key) == normalizeIBAN(account.Identifier)) {
+			return balance, true
+		}
+	}
+	return KnownBalance{}, false
+}
+
+// balanceAt returns the account's balance at the end of a day from its
+// mutations. Without a known balance it starts at zero before the first
+// mutation, and known is false. Known is false as well when the synced
+// periods don't cover every day between the known balance and the day, since
+// mutations could be missing.
+func balanceAt(account FinancialAccount, balances map[string]KnownBalance, mutations []FinancialMutation, synced Periods, date string) (balance float64, known bool) {
+	anchor, known := knownBalance(account, balances)
+	switch {
+	case anchor.Date < date:
+		known = known && synced.covers(nextDay(anchor.Date), date)
+	case anchor.Date > date:
+		known = known && synced.covers(nextDay(date), anchor.Date)
+	}
+	balance = anchor.Balance
+	for _, mut := range mutations {
+		if mut.FinancialAccountID != account.ID {
+			continue
+		}
+		var amount float64
+		fmt.Sscanf(mut.Amount, "%f", &amount)
+		switch {
+		case mut.Date > anchor.Date && mut.Date <= date:
+			balance += amount
+		case mut.Date <= anchor.Date && mut.Date > date:
+			balance -= amount
+		}
+	}
+	return balance, known
+}
+
+// CashFlow is what moved through one financial account in a period
+type CashFlow struct {
+	Account      FinancialAccount
+	Opening      float64
+	Closing      float64
+	KnownBalance bool
+	Inflows      map[string]float64 // category → amount
+	Outflows     map[string]float64 // category → amount, negative
+	Large        []FinancialMutation
+}
+
+// mutationCategories divides a mutation's amount over the ledger accounts it
+// is booked on, or its documents' lines, in proportion to what they got
+func mutationCategories(mut FinancialMutation, snap *Snapshot, accountMap map[string]LedgerAccount) map[string]float64 {
+	var amount float64
+	fmt.Sscanf(mut.Amount, "%f", &amount)
+
+	single := &Snapshot{Mutations: []FinancialMutation{mut}, Documents: snap.Documents, TaxRates: snap.TaxRates, ExchangeRates: snap.ExchangeRates}
+	totals, _, _ := aggregateTotals(single, snap.LedgerAccounts)
+
+	var weight float64
+	for id, total := range totals {
+		if _, ok := accountMap[id]; ok {
+			weight += math.Abs(total)
+		}
+	}
+	if weight == 0 {
+		return map[string]float64{categoryUncategorized: amount}
+	}
+
+	categories := make(map[string]float64)
+	for id, total := range totals {
+		if acc, ok := accountMap[id]; ok {
+			categories[acc.Name] += amount * math.Abs(total) / weight
+		}
+	}
+	return categories
+}
+
+// cashFlows builds the cash flow of every financial account over [start, end].
+// The snapshot holds the period's mutations; the history every stored mutation,
+// to derive the balances from, and synced the periods it is complete for.
+func cashFlows(snap *Snapshot, accounts []FinancialAccount, history []FinancialMutation, synced Periods, balances map[string]KnownBalance, large float64) []CashFlow {
+	accountMap := make(map[string]LedgerAccount)
+	for _, acc := range snap.LedgerAccounts {
+		accountMap[acc.ID] = acc
+	}
+	isTransfer := transferIDs(detectTransfers(snap.Mutations, transferConfigFromEnv()))
+
+	// Mutations on accounts that were never fetched still get a cash flow
+	known := make(map[string]bool)
+	for _, acc := range accounts {
+		known[acc.ID] = true
+	}
+	for _, mut := range snap.Mutations {
+		if mut.FinancialAccountID != "" && !known[mut.FinancialAccountID] {
+			known[mut.FinancialAccountID] = true
+			accounts = append(accounts, FinancialAccount{ID: mut.FinancialAccountID, Name: mut.FinancialAccountID, Active: true})
+		}
+	}
+
+	openingDate := ""
+	if start, err := time.Parse("2006-01-02", snap.PeriodStart); err == nil {
+		openingDate = start.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+
+	var flows []CashFlow
+	for _, account := range accounts {
+		flow := CashFlow{
+			Account:  account,
+			Inflows:  make(map[string]float64),
+			Outflows: make(map[string]float64),
+		}
+		flow.Opening, flow.KnownBalance = balanceAt(account, balances, history, synced, openingDate)
+		flow.Closing, _ = balanceAt(account, balances, history, synced, snap.PeriodEnd)
+
+		var moved bool
+		for _, mut := range snap.Mutations {
+			if mut.FinancialAccountID != account.ID {
+				continue
+			}
+			moved = true
+
+			var amount float64
+			fmt.Sscanf(mut.Amount, "%f", &amount)
+			categories := map[string]float64{categoryInternalTransfers: amount}
+			if !isTransfer[mut.ID] {
+				categories = mutationCategories(mut, snap, accountMap)
+			}
+			for category, part := range categories {
+				if amount >= 0 {
+					flow.Inflows[category] += part
+				} else {
+					flow.Outflows[category] += part
+				}
+			}
+			if math.Abs(amount) >= large {
+				flow.Large = append(flow.Large, mut)
+			}
+		}
+
+		// Inactive accounts without movements are left out
+		if moved || account.Active {
+			flows = append(flows, flow)
+		}
+	}
+
+	sort.Slice(flows, func(i, j int) bool {
+		return flows[i].Account.Name < flows[j].Account.Name
+	})
+	return flows
+}
+
+// printFlowCategories writes the categories largest first and returns their total
+func printFlowCategories(categories map[string]float64) float64 {
+	if len(categories) == 0 {
+		fmt.Println("      (none)")
+		return 0
+	}
+	names := make([]string, 0, len(categories))
+	var total float64
+	for name, amount := range categories {
+		names = append(names, name)
+		total += amount
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if math.Abs(categories[names[i]]) != math.Abs(categories[names[j]]) {
+			return math.Abs(categories[names[i]]) > math.Abs(categories[names[j]])
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		fmt.Printf("      %-30s €%10.2f\n", name, categories[name])
+	}
+	return total
+}
+
+// printCashFlow writes one account's cash flow statement
+func printCashFlow(flow CashFlow) {
+	title := flow.Account.Name
+	if flow.Account.Identifier != "" {
+		title += " (" + flow.Account.Identifier + ")"
+	}
+	if kind := flow.Account.kind(); kind != "" {
+		title += ", " + kind
+	}
+	fmt.Printf("\n%s:\n", title)
+
+	note := ""
+	if !flow.KnownBalance {
+		note = "  (no known balance, relative to the first stored transaction)"
+	}
+	fmt.Printf("   Opening balance:  €%10.2f%s\n", flow.Opening, note)
+
+	fmt.Println("   Inflows:")
+	inflow := printFlowCategories(flow.Inflows)
+	fmt.Println("   Outflows:")
+	outflow := printFlowCategories(flow.Outflows)
+	fmt.Printf("   Net cash flow:    €%10.2f  (in €%.2f, out €%.2f)\n", inflow+outflow, inflow, outflow)
+	fmt.Printf("   Closing balance:  €%10.2f\n", flow.Closing)
+
+	if math.Abs(flow.Opening+inflow+outflow-flow.Closing) >= 0.005 {
+		fmt.Println("   ⚠️  Opening balance plus cash flow doesn't match the closing balance")
+	}
+
+	if len(flow.Large) > 0 {
+		fmt.Println("   Large transactions:")
+		for _, mut := range flow.Large {
+			fmt.Printf("      %s\n", describeMutation(mut))
+		}
+	}
+}
+
+// runCashFlow prints a cash flow statement per financial account for a
+// period, from the store
+func runCashFlow(args []string) {
+	fs := flag.NewFlagSet("cashflow", flag.ExitOnError)
+	storeFile := fs.String("store", defaultStoreFile, "Local store of synced Moneybird data")
+	period := fs.String("period", "", "Month to report (YYYY-MM, default: current month)")
+	from := fs.String("from", "", "Start of the period (YYYY-MM-DD or YYYY-MM), instead of -period")
+	to := fs.String("to", "", "End of the period (YYYY-MM-DD or YYYY-MM), instead of -period")
+	balancesFile := fs.String("balances", defaultBalancesFile, "File with known balances by financial account ID, name or IBAN, for accounts without bank statements")
+	large := fs.Float64("large", defaultLargeTransaction, "Itemize transactions of at least this amount")
+	fs.Parse(args)
+
+	start, end, err := reportPeriod(*period, *from, *to)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	balances, err := loadKnownBalances(*balancesFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	store, err := OpenStore(*storeFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	exchangeRates, err := loadExchangeRates(defaultExchangeRatesFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	snap := store.Snapshot(start, end)
+	snap.ExchangeRates = exchangeRates
+	flows := cashFlows(snap, store.FinancialAccountList(), store.MutationsBetween("", ""), store.SyncedPeriods, balanceAnchors(store, balances), *large)
+
+	fmt.Printf("Cash flow %s to %s:\n", start, end)
+	if len(flows) == 0 {
+		fmt.Println("   No financial accounts")
+	}
+	for _, flow := range flows {
+		printCashFlow(flow)
+	}
+}
+
+// reportPeriod turns the -period, or -from and -to flags into a date range,
+// the current month by default
+func reportPeriod(period, from, to string) (start, end string, err error) {
+	if from != "" || to != "" {
+		if start, err = parseBound(from, false); err != nil {
+			return "", "", err
+		}
+		if end, err = parseBound(to, true); err != nil {
+			return "", "", err
+		}
+		if end == "" {
+			end = time.Now().Format("2006-01-02")
+		}
+		return start, end, nil
+	}
+
+	month := time.Now()
+	if period != "" {
+		if month, err = time.Parse("2006-01", period); err != nil {
+			return "", "", fmt.Errorf("invalid period: %w", err)
+		}
+	}
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return first.Format("2006-01-02"), first.AddDate(0, 1, -1).Format("2006-01-02"), nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBalanceAnchorsPreferStatements(t *testing.T) {
+	store := &Store{
+		FinancialAccounts: map[string]FinancialAccount{
+			"bank": {ID: "bank", Name: "Betaalrekening", Identifier: "NL91 ABNA 0417 1643 00"},
+			"card": {ID: "card", Name: "Creditcard"},
+			"save": {ID: "save", Name: "Spaarrekening"},
+		},
+		Balances: map[string]KnownBalance{
+			"bank": {Date: "2026-01-31", Balance: 1000, StatementID: "st1"},
+		},
+	}
+	file := map[string]KnownBalance{
+		"NL91ABNA0417164300": {Date: "2025-12-31", Balance: 4250.17},
+		"creditcard":         {Date: "2025-12-31", Balance: -312.40},
+	}
+
+	anchors := balanceAnchors(store, file)
+	if anchor := anchors["bank"]; anchor.StatementID != "st1" || anchor.Balance != 1000 {
+		t.Errorf("bank anchor = %+v, want the statement balance", anchor)
+	}
+	if anchor := anchors["card"]; anchor.Balance != -312.40 {
+		t.Errorf("card anchor = %+v, want the balances.json entry", anchor)
+	}
+	if anchor, ok := anchors["save"]; ok {
+		t.Errorf("savings anchor = %+v, want none", anchor)
+	}
+
+	// Balances are derived from the anchor in both directions
+	mutations := []FinancialMutation{
+		{FinancialAccountID: "bank", Date: "2026-01-15", Amount: "-200.00"},
+		{FinancialAccountID: "bank", Date: "2026-02-10", Amount: "50.00"},
+	}
+	account := store.FinancialAccounts["bank"]
+	synced := Periods{}.add("2026-01-01", "2026-01-31").add("2026-02-01", "2026-02-28")
+	if balance, known := balanceAt(account, anchors, mutations, synced, "2026-01-10"); !known || balance != 1200 {
+		t.Errorf("balance before the statement = %.2f, %v, want 1200.00", balance, known)
+	}
+	if balance, _ := balanceAt(account, anchors, mutations, synced, "2026-02-28"); balance != 1050 {
+		t.Errorf("balance after the statement = %.2f, want 1050.00", balance)
+	}
+
+	// Without the mutations of every day in between the balance isn't known
+	if _, known := balanceAt(account, anchors, mutations, Periods{{"2026-02-01", "2026-02-28"}}, "2026-01-10"); known {
+		t.Error("balance before the statement known without January synced")
+	}
+	if _, known := balanceAt(account, anchors, mutations, synced, "2026-03-31"); known {
+		t.Error("balance known for March, which wasn't synced")
+	}
+}
+
+func TestSyncStatementBalances(t *testing.T) {
+	supported := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !supported || r.URL.Path != "/financial_statements/st2.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"id": "st2", "financial_account_id": "bank", "official_date": "2026-02-28", "official_balance": "1050.0"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("token")
+	client.apiURL = server.URL
+	newStore := func() *Store {
+		return &Store{
+			Balances: map[string]KnownBalance{"bank": {Date: "2026-01-31", Balance: 1000, StatementID: "st1"}},
+			FinancialMutations: map[string]FinancialMutation{
+				"m1": {ID: "m1", FinancialAccountID: "bank", Date: "2026-01-15", FinancialStatementID: "st1"},
+				"m2": {ID: "m2", FinancialAccountID: "bank", Date: "2026-02-10", FinancialStatementID: "st2"},
+			},
+		}
+	}
+
+	store := newStore()
+	if updated, err := syncStatementBalances(client, store); err != nil || updated != 1 {
+		t.Fatalf("syncStatementBalances = %d, %v, want 1 balance updated", updated, err)
+	}
+	if balance := store.Balances["bank"]; balance.StatementID != "st2" || balance.Balance != 1050 {
+		t.Errorf("bank balance = %+v, want the latest statement", balance)
+	}
+
+	// Without the endpoint the stored balance stays
+	supported = false
+	store = newStore()
+	if _, err := syncStatementBalances(client, store); err == nil {
+		t.Error("no error while statements can't be fetched")
+	}
+	if balance := store.Balances["bank"]; balance.StatementID != "st1" {
+		t.Errorf("bank balance = %+v, want the stored one", balance)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	categorizeStateFile = "categorize_state.json"
+	maxSuggestions      = 6
+)
+
+// postedMutation records an uncategorized mutation that was sent to Telegram
+type postedMutation struct {
+	MessageID int64     `json:"message_id"`
+	PostedAt  time.Time `json:"posted_at"`
+}
+
+// categorizeState keeps track of which mutations have already been posted,
+// so each one is only offered once
+type categorizeState struct {
+	Posted map[string]postedMutation `json:"posted"`
+}
+
+// loadCategorizeState reads the state file, returning an empty state if it doesn't exist yet
+func loadCategorizeState(filename string) (*categorizeState, error) {
+	state := &categorizeState{Posted: make(map[string]postedMutation)}
+
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("unmarshaling state: %w", err)
+	}
+	if state.Posted == nil {
+		state.Posted = make(map[string]postedMutation)
+	}
+
+	return state, nil
+}
+
+// save writes the state file
+func (s *categorizeState) save(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling state: %w", err)
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// isUncategorized reports whether a mutation still has to be booked
+func isUncategorized(mut FinancialMutation) bool {
+	return mut.State == "unprocessed" && len(mut.LedgerAccountBookings) == 0 && len(mut.Payments) == 0
+}
+
+// suggestLedgerAccounts ranks equity ledger accounts by how likely they fit the mutation.
+// Accounts previously used for the same contra account weigh heaviest, followed by
+// overall usage in the history. Only leaf accounts are suggested.
+func suggestLedgerAccounts(mut FinancialMutation, accounts []LedgerAccount, history []FinancialMutation, limit int) []LedgerAccount {
+	hasChildren := make(map[string]bool)
+	for _, acc := range accounts {
+		if acc.ParentID != nil && *acc.ParentID != "" {
+			hasChildren[*acc.ParentID] = true
+		}
+	}
+
+	contra := strings.ToLower(strings.TrimSpace(mut.ContraAccountName))
+	scores := make(map[string]int)
+	for _, hist := range history {
+		sameContra := contra != "" && strings.ToLower(strings.TrimSpace(hist.ContraAccountName)) == contra
+		for _, booking := range hist.LedgerAccountBookings {
+			scores[booking.LedgerAccountID]++
+			if sameContra {
+				scores[booking.LedgerAccountID] += 10
+			}
+		}
+	}
+
+	var candidates []LedgerAccount
+	for _, acc := range accounts {
+		if acc.AccountType == "equity" && !hasChildren[acc.ID] {
+			candidates = append(candidates, acc)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		si, sj := scores[candidates[i].ID], scores[candidates[j].ID]
+		if si != sj {
+			return si > sj
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// formatMutationMessage renders a mutation as an HTML Telegram message
+func formatMutationMessage(mut FinancialMutation) string {
+	amount, _ := strconv.ParseFloat(mut.Amount, 64)
+
+	message := "<b>🧾 Uncategorized transaction</b>\n\n"
+	message += fmt.Sprintf("%s · €%.2f\n", html.EscapeString(mut.Date), amount)
+	if mut.ContraAccountName != "" {
+		message += html.EscapeString(mut.ContraAccountName) + "\n"
+	}
+	if mut.Message != "" {
+		message += fmt.Sprintf("<i>%s</i>\n", html.EscapeString(mut.Message))
+	}
+	return message
+}
+
+// categorizeKeyboard builds the inline keyboard with one button per suggested account
+func categorizeKeyboard(mutationID string, suggestions []LedgerAccount) *InlineKeyboardMarkup {
+	var rows [][]InlineKeyboardButton
+	var row []InlineKeyboardButton
+	for _, acc := range suggestions {
+		row = append(row, InlineKeyboardButton{
+			Text:         acc.Name,
+			CallbackData: fmt.Sprintf("book:%s:%s", mutationID, acc.ID),
+		})
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, []InlineKeyboardButton{{Text: "Skip", CallbackData: "skip:" + mutationID}})
+
+	return &InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// postUncategorized sends every uncategorized mutation that hasn't been posted before
+// to Telegram, with buttons for the most likely equity ledger accounts based on history
+func postUncategorized(bot *TelegramBot, accounts []LedgerAccount, mutations, history []FinancialMutation, stateFile string) (int, error) {
+	state, err := loadCategorizeState(stateFile)
+	if err != nil {
+		return 0, err
+	}
+
+	model := trainSuggester(history, accounts)
+
+	posted := 0
+	for _, mut := range mutations {
+		if !isUncategorized(mut) {
+			continue
+		}
+		if _, done := state.Posted[mut.ID]; done {
+			continue
+		}
+
+		// The learned suggestion goes first, followed by the history ranking
+		text := formatMutationMessage(mut)
+		suggestions := suggestLedgerAccounts(mut, accounts, history, maxSuggestions)
+		if best, ok := model.best(mut); ok {
+			text += fmt.Sprintf("\n💡 Suggested: <b>%s</b> (%.0f%%)\n", html.EscapeString(best.Account.Name), best.Confidence*100)
+			ranked := []LedgerAccount{best.Account}
+			for _, acc := range suggestions {
+				if acc.ID != best.Account.ID && len(ranked) < maxSuggestions {
+					ranked = append(ranked, acc)
+				}
+			}
+			suggestions = ranked
+		}
+
+		msg, err := bot.SendMessage(text+"\nChoose a category:", categorizeKeyboard(mut.ID, suggestions))
+		if err != nil {
+			// Save what we have so far, so already posted mutations aren't repeated
+			_ = state.save(stateFile)
+			return posted, fmt.Errorf("posting mutation %s: %w", mut.ID, err)
+		}
+
+		state.Posted[mut.ID] = postedMutation{MessageID: msg.MessageID, PostedAt: time.Now()}
+		posted++
+	}
+
+	if err := state.save(stateFile); err != nil {
+		return posted, fmt.Errorf("saving state: %w", err)
+	}
+	return posted, nil
+}
+
+// handleCallback processes a tap on one of the categorization buttons
+func handleCallback(client *Client, bot *TelegramBot, accountMap map[string]LedgerAccount, cq *CallbackQuery) error {
+	if cq.Message == nil || strconv.FormatInt(cq.Message.Chat.ID, 10) != bot.chatID {
+		return bot.AnswerCallbackQuery(cq.ID, "Not allowed")
+	}
+
+	parts := strings.Split(cq.Data, ":")
+	if len(parts) < 2 {
+		return bot.AnswerCallbackQuery(cq.ID, "Unknown action")
+	}
+	action, mutationID := parts[0], parts[1]
+
+	mut, err := client.GetFinancialMutation(mutationID)
+	if err != nil {
+		_ = bot.AnswerCallbackQuery(cq.ID, "Could not load transaction")
+		return fmt.Errorf("fetching mutation %s: %w", mutationID, err)
+	}
+
+	switch action {
+	case "skip":
+		if err := bot.EditMessageText(cq.Message.MessageID, formatMutationMessage(*mut)+"\n⏭ Skipped"); err != nil {
+			return err
+		}
+		return bot.AnswerCallbackQuery(cq.ID, "Skipped")
+
+	case "book":
+		if len(parts) != 3 {
+			return bot.AnswerCallbackQuery(cq.ID, "Unknown action")
+		}
+		if !isUncategorized(*mut) {
+			_ = bot.EditMessageText(cq.Message.MessageID, formatMutationMessage(*mut)+"\nℹ️ Already booked")
+			return bot.AnswerCallbackQuery(cq.ID, "Already booked")
+		}
+
+		acc, ok := accountMap[parts[2]]
+		if !ok {
+			return bot.AnswerCallbackQuery(cq.ID, "Unknown ledger account")
+		}
+
+		if _, err := client.BookOnLedgerAccount(mut.ID, acc.ID, mut.Amount, ""); err != nil {
+			_ = bot.AnswerCallbackQuery(cq.ID, "Booking failed")
+			return fmt.Errorf("booking mutation %s: %w", mut.ID, err)
+		}
+
+		confirmation := fmt.Sprintf("\n✅ Booked as <b>%s</b>", html.EscapeString(acc.Name))
+		if err := bot.EditMessageText(cq.Message.MessageID, formatMutationMessage(*mut)+confirmation); err != nil {
+			return err
+		}
+		return bot.AnswerCallbackQuery(cq.ID, "Booked as "+acc.Name)
+	}
+
+	return bot.AnswerCallbackQuery(cq.ID, "Unknown action")
+}
+
+// handleCommand answers slash commands sent to the bot. Commands read the
+// local store, which is kept up to date by the report runs.
+func handleCommand(bot *TelegramBot, storeFile string, msg *TelegramMessage) error {
+	if strconv.FormatInt(msg.Chat.ID, 10) != bot.chatID || !strings.HasPrefix(msg.Text, "/") {
+		return nil
+	}
+
+	// "/top@MyBot 5" → command "/top", arguments ["5"]
+	fields := strings.Fields(msg.Text)
+	command, _, _ := strings.Cut(fields[0], "@")
+
+	switch command {
+	case "/top":
+		store, err := OpenStore(storeFile)
+		if err != nil {
+			_, _ = bot.SendMessage("Could not open the store", nil)
+			return err
+		}
+
+		n := defaultTopMerchants
+		if len(fields) > 1 {
+			if parsed, err := strconv.Atoi(fields[1]); err == nil && parsed > 0 {
+				n = parsed
+			}
+		}
+
+		now := time.Now()
+		title := fmt.Sprintf("🏪 Top merchants - %s", now.Format("January 2006"))
+		spending := spendingSnapshot(monthSnapshot(store, now), transferConfigFromEnv())
+		merchants := topMerchants(merchantAnalytics(spending), n)
+		_, err = bot.SendMessage(formatMerchantsHTML(title, merchants), nil)
+		return err
+	}
+
+	return nil
+}
+
+// runBot long-polls Telegram and handles categorization button taps and
+// commands until interrupted
+func runBot(client *Client, bot *TelegramBot, storeFile string) {
+	fmt.Println("Fetching ledger accounts...")
+	accounts, err := client.GetLedgerAccounts()
+	if err != nil {
+		fmt.Printf("Error fetching accounts: %v\n", err)
+		os.Exit(1)
+	}
+
+	accountMap := make(map[string]LedgerAccount)
+	for _, acc := range accounts {
+		accountMap[acc.ID] = acc
+	}
+
+	fmt.Println("Listening for Telegram updates...")
+	var offset int64
+	for {
+		updates, err := bot.GetUpdates(offset)
+		if err != nil {
+			fmt.Printf("Error fetching updates: %v\n", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			if update.Message != nil {
+				if err := handleCommand(bot, storeFile, update.Message); err != nil {
+					fmt.Printf("Error handling command: %v\n", err)
+				}
+			}
+			if update.CallbackQuery == nil {
+				continue
+			}
+			if err := handleCallback(client, bot, accountMap, update.CallbackQuery); err != nil {
+				fmt.Printf("Error handling callback: %v\n", err)
+			}
+		}
+	}
+}
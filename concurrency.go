@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Moneybird allows 150 requests per 5 minutes. The limiter allows a burst
+	// of 50, which covers a normal run without waiting, and refills at the
+	// rest of the budget so no 5 minutes hold more than 150 requests.
+	rateLimitBurst     = 50
+	rateLimitPerSecond = (150.0 - rateLimitBurst) / 300.0
+
+	// A request answered with 429 Too Many Requests is retried this many
+	// times, after the Retry-After time or defaultRetryAfter without one
+	rateLimitRetries  = 3
+	defaultRetryAfter = 30 * time.Second
+
+	defaultWorkers = 4
+)
+
+// rateLimiter is a token bucket shared by all requests of a Client
+type rateLimiter struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	rate     float64 // tokens per second
+	last     time.Time
+}
+
+// newRateLimiter creates a full bucket
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		tokens:   float64(burst),
+		capacity: float64(burst),
+		rate:     rate,
+		last:     time.Now(),
+	}
+}
+
+// wait blocks until a request may be made
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.capacity, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	// Take the token now, even if it has yet to be earned; callers that
+	// arrive later queue up behind it
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+// retryAfter parses a Retry-After header, in seconds or an HTTP date
+func retryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return defaultRetryAfter
+}
+
+// forEachConcurrent calls fn for every index in [0, n) using at most workers
+// goroutines. It waits for all calls and returns the error of the lowest
+// index that failed, so the outcome doesn't depend on scheduling. It only fans
+// out; the number of Moneybird requests in flight is capped by the Client.
+func forEachConcurrent(n, workers int, fn func(i int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	errs := make([]error, n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < min(workers, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
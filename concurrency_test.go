@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitFitsMoneybirdWindow(t *testing.T) {
+	// The most requests any 5 minutes can hold: a full bucket plus the refill
+	if most := rateLimitBurst + rateLimitPerSecond*300; most > 150 {
+		t.Errorf("limiter allows %.0f requests per 5 minutes, Moneybird allows 150", most)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter := newRateLimiter(20, 2)
+
+	start := time.Now()
+	limiter.wait()
+	limiter.wait()
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("burst took %v, want no waiting", elapsed)
+	}
+
+	// Two more tokens at 20 per second take 100ms
+	limiter.wait()
+	limiter.wait()
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > time.Second {
+		t.Errorf("four requests took %v, want about 100ms", elapsed)
+	}
+}
+
+func TestForEachConcurrentLowestError(t *testing.T) {
+	var calls atomic.Int32
+	err := forEachConcurrent(20, 4, func(i int) error {
+		calls.Add(1)
+		switch i {
+		case 3:
+			// The lowest failure finishes last
+			time.Sleep(20 * time.Millisecond)
+			return fmt.Errorf("item %d", i)
+		case 15:
+			return fmt.Errorf("item %d", i)
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "item 3" {
+		t.Errorf("error = %v, want the one of item 3", err)
+	}
+	if calls.Load() != 20 {
+		t.Errorf("made %d calls, want all 20", calls.Load())
+	}
+
+	if err := forEachConcurrent(5, 0, func(int) error { return nil }); err != nil {
+		t.Errorf("error = %v, want none", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if got := retryAfter("7"); got != 7*time.Second {
+		t.Errorf("retryAfter(7) = %v", got)
+	}
+	if got := retryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)); got != 0 {
+		t.Errorf("retryAfter(past date) = %v, want 0", got)
+	}
+	if got := retryAfter(""); got != defaultRetryAfter {
+		t.Errorf("retryAfter(\"\") = %v, want the default", got)
+	}
+}
+
+func TestDoRequestRetriesTooManyRequests(t *testing.T) {
+	var requests atomic.Int32
+	limited := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer token" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if int(requests.Add(1)) <= limited {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"error": "Too many requests"}`, http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewClient("token")
+	client.apiURL = server.URL
+	body, err := client.doRequest("GET", "ledger_accounts.json", nil)
+	if err != nil || string(body) != "[]" || requests.Load() != 2 {
+		t.Fatalf("doRequest = %q, %v after %d requests, want a retry", body, err, requests.Load())
+	}
+
+	// It gives up after rateLimitRetries retries
+	requests.Store(0)
+	limited = 10
+	_, err = client.doRequest("GET", "ledger_accounts.json", nil)
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("error = %v, want the 429", err)
+	}
+	if int(requests.Load()) != rateLimitRetries+1 {
+		t.Errorf("made %d requests, want %d", requests.Load(), rateLimitRetries+1)
+	}
+}
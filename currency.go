@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// baseCurrency is the administration's currency that reports are in
+	baseCurrency = "EUR"
+
+	defaultExchangeRatesFile = "exchange_rates.json"
+)
+
+// loadExchangeRates reads the exchange rate table, the value of one unit of a
+// currency in euros, e.g. {"USD": 0.92}. A missing file means no rates.
+func loadExchangeRates(filename string) (map[string]float64, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading exchange rates: %w", err)
+	}
+
+	var file map[string]float64
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("unmarshaling exchange rates: %w", err)
+	}
+
+	rates := make(map[string]float64)
+	for currency, rate := range file {
+		if rate <= 0 {
+			return nil, fmt.Errorf("exchange rate for %s is %g, it has to be positive", currency, rate)
+		}
+		rates[strings.ToUpper(currency)] = rate
+	}
+	return rates, nil
+}
+
+// isForeignCurrency reports whether an amount in the currency needs converting
+func isForeignCurrency(currency string) bool {
+	return currency != "" && !strings.EqualFold(currency, baseCurrency)
+}
+
+// Conversion sources, from most to least exact
+const (
+	conversionNone      = ""           // already in the base currency
+	conversionPriceBase = "price_base" // Moneybird's own base amount
+	conversionDocument  = "document"   // the document's base total
+	conversionRateTable = "rate table" // the offline exchange rate table
+	conversionMissing   = "unconverted"
+)
+
+// documentPayment converts a payment of a document to the base currency. It
+// returns the base amount, the factor to convert the document's own amounts
+// with and where the conversion came from. Payments that can't be converted
+// are counted at face value.
+func (s *Snapshot) documentPayment(payment Payment, doc Document) (base, factor float64, source string) {
+	var price float64
+	fmt.Sscanf(payment.Price, "%f", &price)
+
+	if payment.PriceBase != "" && payment.PriceBase != payment.Price {
+		fmt.Sscanf(payment.PriceBase, "%f", &base)
+		if price != 0 {
+			return base, base / price, conversionPriceBase
+		}
+	}
+	if !isForeignCurrency(doc.Currency) {
+		return price, 1, conversionNone
+	}
+
+	var total, totalBase float64
+	fmt.Sscanf(doc.TotalPriceInclTax, "%f", &total)
+	fmt.Sscanf(doc.TotalPriceInclTaxBase, "%f", &totalBase)
+	if total != 0 && totalBase != 0 {
+		factor = totalBase / total
+		return price * factor, factor, conversionDocument
+	}
+	if rate, ok := s.ExchangeRates[strings.ToUpper(doc.Currency)]; ok {
+		return price * rate, rate, conversionRateTable
+	}
+	return price, 1, conversionMissing
+}
+
+// paymentBase returns a payment's amount in the base currency, preferring
+// price_base over the price
+func paymentBase(payment Payment) float64 {
+	var amount float64
+	if payment.PriceBase != "" {
+		fmt.Sscanf(payment.PriceBase, "%f", &amount)
+	} else {
+		fmt.Sscanf(payment.Price, "%f", &amount)
+	}
+	return amount
+}
+
+// CurrencyTotal is what was paid on documents in one foreign currency
+type CurrencyTotal struct {
+	Currency    string
+	Original    float64
+	Base        float64
+	Unconverted int // payments counted at face value for lack of a rate
+	Sources     []string
+}
+
+// foreignCurrencyTotals totals the document payments of the snapshot per
+// foreign currency, in the original currency and in euros
+func foreignCurrencyTotals(snap *Snapshot) []CurrencyTotal {
+	byCurrency := make(map[string]*CurrencyTotal)
+	sources := make(map[string]map[string]bool)
+	for _, mut := range snap.Mutations {
+		for _, payment := range mut.Payments {
+			doc, ok := snap.Documents[payment.InvoiceID]
+			if payment.InvoiceType != "Document" || !ok || !isForeignCurrency(doc.Currency) {
+				continue
+			}
+			currency := strings.ToUpper(doc.Currency)
+			total, ok := byCurrency[currency]
+			if !ok {
+				total = &CurrencyTotal{Currency: currency}
+				byCurrency[currency] = total
+				sources[currency] = make(map[string]bool)
+			}
+
+			var price float64
+			fmt.Sscanf(payment.Price, "%f", &price)
+			base, _, source := snap.documentPayment(payment, doc)
+			total.Original += math.Abs(price)
+			total.Base += math.Abs(base)
+			if source == conversionMissing {
+				total.Unconverted++
+			}
+			if !sources[currency][source] {
+				sources[currency][source] = true
+				total.Sources = append(total.Sources, source)
+			}
+		}
+	}
+
+	var totals []CurrencyTotal
+	for _, total := range byCurrency {
+		sort.Strings(total.Sources)
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Currency < totals[j].Currency
+	})
+	return totals
+}
+
+// printCurrencyTotals lists the foreign currencies paid in and warns about
+// amounts that were counted without converting them
+func printCurrencyTotals(totals []CurrencyTotal) {
+	for _, total := range totals {
+		fmt.Printf("   %s %10.2f  = €%9.2f  (%s)\n", total.Currency, total.Original, total.Base, strings.Join(total.Sources, ", "))
+		if total.Unconverted > 0 {
+			fmt.Printf("   ⚠️  %d %s payments have no base amount and no exchange rate, counted as euros\n",
+				total.Unconverted, total.Currency)
+		}
+	}
+}
+
+// addExchangeRates adds the rates from the exchange rate file to the snapshot,
+// so a snapshot keeps the rates it was reported with. Rates in the file
+// replace those already in the snapshot.
+func addExchangeRates(snap *Snapshot, filename string) error {
+	rates, err := loadExchangeRates(filename)
+	if err != nil {
+		return err
+	}
+	if len(rates) > 0 && snap.ExchangeRates == nil {
+		snap.ExchangeRates = make(map[string]float64)
+	}
+	for currency, rate := range rates {
+		snap.ExchangeRates[currency] = rate
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAggregateTotalsConvertsForeignPayments(t *testing.T) {
+	accounts := []LedgerAccount{
+		{ID: "omzet", Name: "Omzet", AccountType: "revenue"},
+		{ID: "software", Name: "Software", AccountType: "expenses"},
+	}
+	usdDocument := func(id, totalBase string) Document {
+		return Document{
+			ID:                    id,
+			Currency:              "USD",
+			TotalPriceInclTax:     "100.00",
+			TotalPriceInclTaxBase: totalBase,
+			PricesAreInclTax:      true,
+			Details:               []DocumentDetail{{LedgerAccountID: "software", Price: "100.00"}},
+		}
+	}
+	documentPayment := func(id, price, priceBase string) FinancialMutation {
+		return FinancialMutation{ID: id, Payments: []Payment{{InvoiceType: "Document", InvoiceID: id, Price: price, PriceBase: priceBase}}}
+	}
+
+	tests := []struct {
+		name   string
+		doc    Document
+		mut    FinancialMutation
+		rates  map[string]float64
+		want   float64
+		source string
+	}{
+		{"price base", usdDocument("a", "95.00"), documentPayment("a", "100.00", "90.00"), nil, -90, conversionPriceBase},
+		{"document base total", usdDocument("b", "95.00"), documentPayment("b", "100.00", ""), nil, -95, conversionDocument},
+		{"rate table", usdDocument("c", ""), documentPayment("c", "100.00", ""), map[string]float64{"USD": 0.92}, -92, conversionRateTable},
+		{"no rate", usdDocument("d", ""), documentPayment("d", "100.00", ""), nil, -100, conversionMissing},
+	}
+	for _, tt := range tests {
+		snap := &Snapshot{
+			Mutations:     []FinancialMutation{tt.mut},
+			Documents:     map[string]Document{tt.doc.ID: tt.doc},
+			ExchangeRates: tt.rates,
+		}
+		totals, _, _ := aggregateTotals(snap, accounts)
+		if math.Abs(totals["software"]-tt.want) > 0.005 {
+			t.Errorf("%s: software = %.2f, want %.2f", tt.name, totals["software"], tt.want)
+		}
+		if _, _, source := snap.documentPayment(tt.mut.Payments[0], tt.doc); source != tt.source {
+			t.Errorf("%s: converted with %q, want %q", tt.name, source, tt.source)
+		}
+	}
+
+	// Sales invoices count their base amount as revenue
+	snap := &Snapshot{Mutations: []FinancialMutation{{
+		ID:       "sale",
+		Payments: []Payment{{InvoiceType: "SalesInvoice", InvoiceID: "inv", Price: "500.00", PriceBase: "460.00"}},
+	}}}
+	if totals, _, _ := aggregateTotals(snap, accounts); totals["omzet"] != 460 {
+		t.Errorf("omzet = %.2f, want the 460.00 base amount", totals["omzet"])
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MutationChange is a mutation present in both snapshots whose contents differ
+type MutationChange struct {
+	Old, New FinancialMutation
+	Fields   []string // human readable descriptions of what changed
+	// Recategorized is set when the ledger accounts the amount is booked on changed
+	Recategorized bool
+	OldAccounts   []string
+	NewAccounts   []string
+}
+
+// CategoryDelta is the net effect of all changes on a single ledger account
+type CategoryDelta struct {
+	Name     string
+	Type     string
+	Old, New float64
+}
+
+// SnapshotDiff describes how the data changed between two snapshots
+type SnapshotDiff struct {
+	Added   []FinancialMutation
+	Removed []FinancialMutation
+	Changed []MutationChange
+	Deltas  []CategoryDelta
+}
+
+// loadDiffSide reads either a snapshot or a store file and returns it as a
+// snapshot. Store files are limited to the given period (YYYY-MM, or all data
+// when empty).
+func loadDiffSide(filename, period string) (*Snapshot, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", filename, err)
+	}
+
+	if _, isStore := probe["financial_mutations"]; !isStore {
+		return loadSnapshot(filename)
+	}
+
+	store, err := OpenStore(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := "", ""
+	if period != "" {
+		start, end = period+"-01", period+"-31"
+	}
+	return store.Snapshot(start, end), nil
+}
+
+// ledgerAccountNames maps account IDs to names, preferring the newer snapshot
+func ledgerAccountNames(snaps ...*Snapshot) map[string]LedgerAccount {
+	accounts := make(map[string]LedgerAccount)
+	for _, snap := range snaps {
+		for _, acc := range snap.LedgerAccounts {
+			accounts[acc.ID] = acc
+		}
+	}
+	return accounts
+}
+
+// mutationAllocation returns how a single mutation is spread over ledger accounts
+func mutationAllocation(mut FinancialMutation, snap *Snapshot) map[string]float64 {
+	single := &Snapshot{Mutations: []FinancialMutation{mut}, Documents: snap.Documents, TaxRates: snap.TaxRates, ExchangeRates: snap.ExchangeRates}
+	totals, _, _ := aggregateTotals(single, snap.LedgerAccounts)
+	return totals
+}
+
+// allocationAccounts lists the account names with a non-zero share, sorted
+func allocationAccounts(allocation map[string]float64, accounts map[string]LedgerAccount) []string {
+	var names []string
+	for id, amount := range allocation {
+		if math.Abs(amount) < 0.005 {
+			continue
+		}
+		name := id
+		if acc, ok := accounts[id]; ok {
+			name = acc.Name
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// diffSnapshots compares two snapshots mutation by mutation and category by category
+func diffSnapshots(oldSnap, newSnap *Snapshot) SnapshotDiff {
+	var diff SnapshotDiff
+	accounts := ledgerAccountNames(oldSnap, newSnap)
+
+	oldByID := make(map[string]FinancialMutation)
+	for _, mut := range oldSnap.Mutations {
+		oldByID[mut.ID] = mut
+	}
+	newByID := make(map[string]FinancialMutation)
+	for _, mut := range newSnap.Mutations {
+		newByID[mut.ID] = mut
+	}
+
+	for _, mut := range newSnap.Mutations {
+		old, ok := oldByID[mut.ID]
+		if !ok {
+			diff.Added = append(diff.Added, mut)
+			continue
+		}
+		if reflect.DeepEqual(old, mut) {
+			continue
+		}
+
+		change := MutationChange{Old: old, New: mut}
+		if old.Amount != mut.Amount {
+			change.Fields = append(change.Fields, fmt.Sprintf("amount %s → %s", old.Amount, mut.Amount))
+		}
+		if old.Date != mut.Date {
+			change.Fields = append(change.Fields, fmt.Sprintf("date %s → %s", old.Date, mut.Date))
+		}
+		if old.State != mut.State {
+			change.Fields = append(change.Fields, fmt.Sprintf("state %s → %s", old.State, mut.State))
+		}
+
+		change.OldAccounts = allocationAccounts(mutationAllocation(old, oldSnap), accounts)
+		change.NewAccounts = allocationAccounts(mutationAllocation(mut, newSnap), accounts)
+		if !reflect.DeepEqual(change.OldAccounts, change.NewAccounts) {
+			change.Recategorized = true
+			change.Fields = append(change.Fields, fmt.Sprintf("booked on %s → %s",
+				formatAccountList(change.OldAccounts), formatAccountList(change.NewAccounts)))
+		}
+		if len(change.Fields) == 0 {
+			change.Fields = append(change.Fields, "details updated")
+		}
+
+		diff.Changed = append(diff.Changed, change)
+	}
+
+	for _, mut := range oldSnap.Mutations {
+		if _, ok := newByID[mut.ID]; !ok {
+			diff.Removed = append(diff.Removed, mut)
+		}
+	}
+
+	// Net effect on each category
+	oldTotals, _, _ := aggregateTotals(oldSnap, oldSnap.LedgerAccounts)
+	newTotals, _, _ := aggregateTotals(newSnap, newSnap.LedgerAccounts)
+	ids := make(map[string]bool)
+	for id := range oldTotals {
+		ids[id] = true
+	}
+	for id := range newTotals {
+		ids[id] = true
+	}
+	for id := range ids {
+		if math.Abs(newTotals[id]-oldTotals[id]) < 0.005 {
+			continue
+		}
+		delta := CategoryDelta{Name: id, Old: oldTotals[id], New: newTotals[id]}
+		if acc, ok := accounts[id]; ok {
+			delta.Name = acc.Name
+			delta.Type = acc.AccountType
+		}
+		diff.Deltas = append(diff.Deltas, delta)
+	}
+	sort.Slice(diff.Deltas, func(i, j int) bool {
+		di := math.Abs(diff.Deltas[i].New - diff.Deltas[i].Old)
+		dj := math.Abs(diff.Deltas[j].New - diff.Deltas[j].Old)
+		if di != dj {
+			return di > dj
+		}
+		return diff.Deltas[i].Name < diff.Deltas[j].Name
+	})
+
+	sortMutations(diff.Added)
+	sortMutations(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		if diff.Changed[i].New.Date != diff.Changed[j].New.Date {
+			return diff.Changed[i].New.Date < diff.Changed[j].New.Date
+		}
+		return diff.Changed[i].New.ID < diff.Changed[j].New.ID
+	})
+
+	return diff
+}
+
+// formatAccountList joins account names for display
+func formatAccountList(names []string) string {
+	if len(names) == 0 {
+		return "(nothing)"
+	}
+	return strings.Join(names, " + ")
+}
+
+// describeMutation formats a mutation on a single line
+func describeMutation(mut FinancialMutation) string {
+	var amount float64
+	fmt.Sscanf(mut.Amount, "%f", &amount)
+	desc := fmt.Sprintf("%s  €%9.2f  %s", mut.Date, amount, mut.ContraAccountName)
+	if mut.Message != "" {
+		desc += " - " + mut.Message
+	}
+	return desc
+}
+
+// printDiff writes the diff to the console
+func printDiff(diff SnapshotDiff) {
+	fmt.Printf("\nNew transactions (%d):\n", len(diff.Added))
+	for _, mut := range diff.Added {
+		fmt.Printf("   + %s\n", describeMutation(mut))
+	}
+
+	fmt.Printf("\nDeleted transactions (%d):\n", len(diff.Removed))
+	for _, mut := range diff.Removed {
+		fmt.Printf("   - %s\n", describeMutation(mut))
+	}
+
+	var recategorized int
+	fmt.Printf("\nChanged transactions (%d):\n", len(diff.Changed))
+	for _, change := range diff.Changed {
+		marker := "~"
+		if change.Recategorized {
+			marker = "↻"
+			recategorized++
+		}
+		fmt.Printf("   %s %s\n", marker, describeMutation(change.New))
+		for _, field := range change.Fields {
+			fmt.Printf("       %s\n", field)
+		}
+	}
+	fmt.Printf("   (%d re-categorized)\n", recategorized)
+
+	fmt.Println("\nNet effect per category:")
+	if len(diff.Deltas) == 0 {
+		fmt.Println("   No category totals changed")
+	}
+	for _, delta := range diff.Deltas {
+		fmt.Printf("   %-30s €%10.2f → €%10.2f  (%+.2f)\n", delta.Name, delta.Old, delta.New, delta.New-delta.Old)
+	}
+}
+
+// runDiff compares two snapshots, or the previous and current store
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	useStore := fs.Bool("store", false, "Compare the store before and after the last sync")
+	storeFile := fs.String("store-file", defaultStoreFile, "Local store file")
+	period := fs.String("period", "", "Limit store comparisons to a month (YYYY-MM)")
+	fs.Usage = func() {
+		fmt.Println("Usage: financial-tracker diff OLD.json NEW.json")
+		fmt.Println("       financial-tracker diff --store [--period YYYY-MM]")
+		fmt.Println("\nOLD and NEW can be snapshots (financial_data_*.json) or store files.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	oldFile, newFile := fs.Arg(0), fs.Arg(1)
+	if *useStore {
+		oldFile, newFile = *storeFile+previousStoreSuffix, *storeFile
+	}
+	if oldFile == "" || newFile == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	oldSnap, err := loadDiffSide(oldFile, *period)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	newSnap, err := loadDiffSide(newFile, *period)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Comparing %s (%d transactions) with %s (%d transactions)\n",
+		oldFile, len(oldSnap.Mutations), newFile, len(newSnap.Mutations))
+
+	printDiff(diffSnapshots(oldSnap, newSnap))
+}
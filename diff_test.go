@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	accounts := []LedgerAccount{
+		{ID: "food", Name: "Boodschappen", AccountType: "equity"},
+		{ID: "car", Name: "Auto", AccountType: "equity"},
+	}
+	booked := func(mut FinancialMutation, accountID string) FinancialMutation {
+		mut.LedgerAccountBookings = []LedgerAccountBooking{{LedgerAccountID: accountID, Price: mut.Amount}}
+		return mut
+	}
+	shop := FinancialMutation{ID: "shop", Date: "2026-03-02", Amount: "-40.00", ContraAccountName: "Jumbo"}
+	fuel := FinancialMutation{ID: "fuel", Date: "2026-03-04", Amount: "-60.00", ContraAccountName: "Shell"}
+	gone := FinancialMutation{ID: "gone", Date: "2026-03-01", Amount: "-5.00", ContraAccountName: "Kiosk"}
+	added := FinancialMutation{ID: "added", Date: "2026-03-09", Amount: "-12.00", ContraAccountName: "Bakker"}
+
+	oldSnap := &Snapshot{LedgerAccounts: accounts, Mutations: []FinancialMutation{
+		booked(shop, "food"), fuel, booked(gone, "food"),
+	}}
+	newSnap := &Snapshot{LedgerAccounts: accounts, Mutations: []FinancialMutation{
+		booked(shop, "car"), booked(fuel, "car"), booked(added, "food"),
+	}}
+
+	diff := diffSnapshots(oldSnap, newSnap)
+	if len(diff.Added) != 1 || diff.Added[0].ID != "added" {
+		t.Errorf("added = %v, want the new mutation", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].ID != "gone" {
+		t.Errorf("removed = %v, want the deleted mutation", diff.Removed)
+	}
+	if len(diff.Changed) != 2 {
+		t.Fatalf("changed = %v, want the re-booked and newly booked mutations", diff.Changed)
+	}
+	for _, change := range diff.Changed {
+		if !change.Recategorized || !reflect.DeepEqual(change.NewAccounts, []string{"Auto"}) {
+			t.Errorf("%s: %+v, want re-categorized to Auto", change.New.ID, change)
+		}
+	}
+	if fields := diff.Changed[0].Fields; !reflect.DeepEqual(fields, []string{"booked on Boodschappen → Auto"}) {
+		t.Errorf("shop fields = %v", fields)
+	}
+	if fields := diff.Changed[1].Fields; !reflect.DeepEqual(fields, []string{"booked on (nothing) → Auto"}) {
+		t.Errorf("fuel fields = %v", fields)
+	}
+
+	// Auto gained both, Boodschappen lost 45 and gained 12
+	want := []CategoryDelta{
+		{Name: "Auto", Type: "equity", Old: 0, New: -100},
+		{Name: "Boodschappen", Type: "equity", Old: -45, New: -12},
+	}
+	if !reflect.DeepEqual(diff.Deltas, want) {
+		t.Errorf("deltas = %+v, want %+v", diff.Deltas, want)
+	}
+}
+
+func TestLoadDiffSideLimitsStoresToThePeriod(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	store, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.PutFinancialMutation(FinancialMutation{ID: "feb", Date: "2026-02-27", Amount: "-1.00"})
+	store.PutFinancialMutation(FinancialMutation{ID: "mar", Date: "2026-03-31", Amount: "-2.00"})
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := loadDiffSide(path, "2026-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Mutations) != 1 || snap.Mutations[0].ID != "mar" {
+		t.Errorf("mutations = %v, want only March", snap.Mutations)
+	}
+	if snap, _ = loadDiffSide(path, ""); len(snap.Mutations) != 2 {
+		t.Errorf("mutations = %v, want all of them without a period", snap.Mutations)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// documentTypes are the document endpoints a payment's document can live under.
+// Payments only say "Document", the endpoint has to be looked up.
+var documentTypes = []string{
+	"purchase_invoices",
+	"receipts",
+	"general_journal_documents",
+	"typeless_documents",
+}
+
+// ResolveDocumentTypes works out the document type of every ID by listing the
+// IDs each document endpoint knows. IDs that no endpoint knows are left out.
+func (c *Client) ResolveDocumentTypes(ids []string) (map[string]string, error) {
+	wanted := make(map[string]bool)
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	remotes := make([][]SyncVersion, len(documentTypes))
+	err := forEachConcurrent(len(documentTypes), c.workers, func(i int) error {
+		remote, err := c.GetSyncVersions("documents/"+documentTypes[i], "")
+		if err != nil {
+			return fmt.Errorf("listing %s: %w", documentTypes[i], err)
+		}
+		remotes[i] = remote
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]string)
+	for i, docType := range documentTypes {
+		for _, rv := range remotes[i] {
+			if wanted[rv.ID] {
+				types[rv.ID] = docType
+			}
+		}
+	}
+	return types, nil
+}
+
+// GetDocuments fetches documents of any type, each from its own endpoint. IDs
+// that don't resolve to a document are returned as missing.
+func (c *Client) GetDocuments(ids []string) (docs []Document, missing []string, err error) {
+	types, err := c.ResolveDocumentTypes(ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving document types: %w", err)
+	}
+
+	byType := make([][]string, len(documentTypes))
+	for _, id := range ids {
+		docType, ok := types[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		for i := range documentTypes {
+			if documentTypes[i] == docType {
+				byType[i] = append(byType[i], id)
+			}
+		}
+	}
+
+	fetched := make([][]Document, len(documentTypes))
+	err = forEachConcurrent(len(documentTypes), c.workers, func(i int) error {
+		if len(byType[i]) == 0 {
+			return nil
+		}
+		docs, err := c.GetDocumentsBatch(byType[i], documentTypes[i])
+		if err != nil {
+			return fmt.Errorf("fetching %s: %w", documentTypes[i], err)
+		}
+		fetched[i] = docs
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, batch := range fetched {
+		docs = append(docs, batch...)
+	}
+	sort.Strings(missing)
+	return docs, missing, nil
+}
+
+// lines returns how a document is spread over ledger accounts. General journal
+// documents have debit and credit entries instead of details.
+func (d Document) lines() []DocumentDetail {
+	if len(d.Details) > 0 || len(d.GeneralJournalEntries) == 0 {
+		return d.Details
+	}
+
+	lines := make([]DocumentDetail, 0, len(d.GeneralJournalEntries))
+	for _, entry := range d.GeneralJournalEntries {
+		var debit, credit float64
+		fmt.Sscanf(entry.Debit, "%f", &debit)
+		fmt.Sscanf(entry.Credit, "%f", &credit)
+		lines = append(lines, DocumentDetail{
+			ID:              entry.ID,
+			LedgerAccountID: entry.LedgerAccountID,
+			Price:           fmt.Sprintf("%.2f", debit-credit),
+		})
+	}
+	return lines
+}
+
+// documentTypeCounts summarizes fetched documents per type for the console
+func documentTypeCounts(docs []Document) string {
+	counts := make(map[string]int)
+	for _, doc := range docs {
+		counts[doc.Type]++
+	}
+
+	var summary string
+	for _, docType := range documentTypes {
+		if counts[docType] == 0 {
+			continue
+		}
+		if summary != "" {
+			summary += ", "
+		}
+		summary += fmt.Sprintf("%d %s", counts[docType], docType)
+	}
+	if summary == "" {
+		return "none"
+	}
+	return summary
+}
+
+// contactName returns who the document is from, for display
+func (d Document) contactName() string {
+	if d.Contact != nil && d.Contact.Name() != "" {
+		return d.Contact.Name()
+	}
+	return "(no contact)"
+}
+
+// paidShare returns the part of the document a payment covers, so partial
+// payments only count their share of each detail. It is 1 when the document
+// total is unknown.
+func (d Document) paidShare(paymentPrice string) float64 {
+	var total, paid float64
+	fmt.Sscanf(d.TotalPriceInclTax, "%f", &total)
+	fmt.Sscanf(paymentPrice, "%f", &paid)
+	if total == 0 || paid == 0 {
+		return 1
+	}
+	return math.Abs(paid / total)
+}
+
+// printDocumentDrillDown lists the documents paid in the snapshot's period with
+// what was bought on each line
+func printDocumentDrillDown(snap *Snapshot, accountMap map[string]LedgerAccount) {
+	var docs []Document
+	factors := make(map[string]float64) // document ID → conversion to euros
+	for _, mut := range snap.Mutations {
+		for _, payment := range mut.Payments {
+			doc, ok := snap.Documents[payment.InvoiceID]
+			if _, seen := factors[doc.ID]; payment.InvoiceType != "Document" || !ok || seen {
+				continue
+			}
+			_, factors[doc.ID], _ = snap.documentPayment(payment, doc)
+			docs = append(docs, doc)
+		}
+	}
+	if len(docs) == 0 {
+		return
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Date != docs[j].Date {
+			return docs[i].Date < docs[j].Date
+		}
+		return docs[i].ID < docs[j].ID
+	})
+
+	rates := taxRateMap(snap.TaxRates)
+	fmt.Println("\nPurchases (from documents):")
+	for _, doc := range docs {
+		var total float64
+		fmt.Sscanf(doc.TotalPriceInclTax, "%f", &total)
+		totalText := fmt.Sprintf("€%9.2f", total)
+		if isForeignCurrency(doc.Currency) {
+			totalText = fmt.Sprintf("%s %.2f (€%.2f)", doc.Currency, total, total*factors[doc.ID])
+		}
+		fmt.Printf("   %s  %-30s %s  (%s)\n", doc.Date, doc.contactName(), totalText, doc.State)
+
+		for _, docLine := range documentLines(doc, rates) {
+			line, price := docLine.Detail, docLine.Total()
+
+			category := line.LedgerAccountID
+			if acc, ok := accountMap[line.LedgerAccountID]; ok {
+				category = acc.Name
+			}
+			description := line.Description
+			if description == "" {
+				description = "(no description)"
+			}
+			if amount := strings.TrimSpace(strings.TrimSuffix(line.Amount, "x")); amount != "" && amount != "1" {
+				description = amount + " × " + description
+			}
+			priceText := fmt.Sprintf("€%9.2f", price)
+			if isForeignCurrency(doc.Currency) {
+				priceText = fmt.Sprintf("%s %.2f", doc.Currency, price)
+			}
+			fmt.Printf("      - %-40s %s  %s\n", description, priceText, category)
+		}
+	}
+}
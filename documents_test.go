@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDocumentsUsesEachTypesEndpoint(t *testing.T) {
+	api, client := newFakeSyncAPI(t, map[string][]map[string]interface{}{
+		"documents/purchase_invoices": {{"id": "inv", "version": 1, "total_price_incl_tax": "121.0"}},
+		"documents/receipts":          {{"id": "rec", "version": 1, "total_price_incl_tax": "9.5"}},
+		"documents/general_journal_documents": {{"id": "memo", "version": 1, "general_journal_document_entries": []map[string]string{
+			{"ledger_account_id": "food", "debit": "30.0", "credit": "0.0"},
+			{"ledger_account_id": "bank", "debit": "0.0", "credit": "30.0"},
+		}}},
+	})
+
+	docs, missing, err := client.GetDocuments([]string{"inv", "rec", "memo", "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]string)
+	for _, doc := range docs {
+		types[doc.ID] = doc.Type
+	}
+	want := map[string]string{"inv": "purchase_invoices", "rec": "receipts", "memo": "general_journal_documents"}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("document types = %v, want %v", types, want)
+	}
+	if !reflect.DeepEqual(missing, []string{"unknown"}) {
+		t.Errorf("missing = %v, want the unknown ID", missing)
+	}
+	for resource, ids := range map[string][]string{
+		"documents/purchase_invoices":         {"inv"},
+		"documents/receipts":                  {"rec"},
+		"documents/general_journal_documents": {"memo"},
+		"documents/typeless_documents":        {},
+	} {
+		if fetched := api.fetchedIDs(resource); len(fetched) != len(ids) || (len(ids) > 0 && !reflect.DeepEqual(fetched, ids)) {
+			t.Errorf("fetched %v from %s, want %v", fetched, resource, ids)
+		}
+	}
+
+	// General journal documents are spread over their entries
+	for _, doc := range docs {
+		if doc.ID != "memo" {
+			continue
+		}
+		lines := doc.lines()
+		if len(lines) != 2 || lines[0].Price != "30.00" || lines[1].Price != "-30.00" {
+			t.Errorf("journal lines = %+v, want a debit and a credit line", lines)
+		}
+	}
+}
+
+func TestSyncDocumentsPerType(t *testing.T) {
+	api, client := newFakeSyncAPI(t, map[string][]map[string]interface{}{
+		"documents/purchase_invoices": {{"id": "inv", "version": 2}},
+		"documents/receipts":          {{"id": "rec", "version": 1}, {"id": "other", "version": 1}},
+	})
+
+	store, err := OpenStore(t.TempDir() + "/store.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.PutDocument(Document{ID: "inv", Type: "purchase_invoices", Version: 1})
+	store.PutDocument(Document{ID: "deleted", Type: "receipts", Version: 1})
+
+	stats, missing, err := syncDocuments(client, store, map[string]bool{"rec": true, "gone": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (SyncStats{Added: 1, Updated: 1, Removed: 1}) {
+		t.Errorf("stats = %v", stats)
+	}
+	if !reflect.DeepEqual(missing, []string{"gone"}) {
+		t.Errorf("missing = %v, want the document no endpoint knows", missing)
+	}
+	if fetched := api.fetchedIDs("documents/receipts"); !reflect.DeepEqual(fetched, []string{"rec"}) {
+		t.Errorf("fetched receipts %v, want only the referenced one", fetched)
+	}
+	if doc := store.Documents["rec"]; doc.Type != "receipts" {
+		t.Errorf("receipt = %+v, want it stored with its type", doc)
+	}
+	if doc := store.Documents["inv"]; doc.Version != 2 {
+		t.Errorf("invoice = %+v, want the new version", doc)
+	}
+	if _, ok := store.Documents["deleted"]; ok {
+		t.Error("document deleted in Moneybird is still stored")
+	}
+}
@@ -7,15 +7,11 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"os"
-	"sort"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/wcharczuk/go-chart/v2"
-	"github.com/wcharczuk/go-chart/v2/drawing"
 )
 
 const (
@@ -26,6 +22,7 @@ const (
 // LedgerAccount represents a Moneybird ledger account
 type LedgerAccount struct {
 	ID                   string    `json:"id"`
+	Version              int64     `json:"version"`
 	AdministrationID     string    `json:"administration_id"`
 	Name                 string    `json:"name"`
 	AccountType          string    `json:"account_type"`
@@ -77,6 +74,7 @@ type LedgerAccountBooking struct {
 // FinancialMutation represents a Moneybird financial mutation (transaction)
 type FinancialMutation struct {
 	ID                    string                 `json:"id"`
+	Version               int64                  `json:"version"`
 	AdministrationID      string                 `json:"administration_id"`
 	Amount                string                 `json:"amount"`
 	Code                  string                 `json:"code"`
@@ -87,6 +85,7 @@ type FinancialMutation struct {
 	State                 string                 `json:"state"`
 	LedgerAccountID       string                 `json:"ledger_account_id"`
 	FinancialAccountID    string                 `json:"financial_account_id"`
+	FinancialStatementID  string                 `json:"financial_statement_id"`
 	Payments              []Payment              `json:"payments"`
 	LedgerAccountBookings []LedgerAccountBooking `json:"ledger_account_bookings"`
 	CreatedAt             time.Time              `json:"created_at"`
@@ -95,64 +94,148 @@ type FinancialMutation struct {
 
 // DocumentDetail represents a line item in a document
 type DocumentDetail struct {
+	ID                            string  `json:"id"`
+	LedgerAccountID               string  `json:"ledger_account_id"`
+	ProjectID                     *string `json:"project_id"`
+	TaxRateID                     string  `json:"tax_rate_id"`
+	Description                   string  `json:"description"`
+	Amount                        string  `json:"amount"` // quantity, e.g. "1" or "2 x"
+	Price                         string  `json:"price"`
+	TotalPriceExclTaxWithDiscount string  `json:"total_price_excl_tax_with_discount"`
+}
+
+// Contact represents a Moneybird contact, the other party of a document
+type Contact struct {
+	ID                  string    `json:"id"`
+	Version             int64     `json:"version"`
+	CompanyName         string    `json:"company_name"`
+	Firstname           string    `json:"firstname"`
+	Lastname            string    `json:"lastname"`
+	SepaIBAN            string    `json:"sepa_iban"`
+	SepaIBANAccountName string    `json:"sepa_iban_account_name"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// Name returns the company name, or the person's name for private contacts
+func (c Contact) Name() string {
+	if c.CompanyName != "" {
+		return c.CompanyName
+	}
+	return strings.TrimSpace(c.Firstname + " " + c.Lastname)
+}
+
+// GeneralJournalDocumentEntry represents a debit or credit line in a general journal document
+type GeneralJournalDocumentEntry struct {
 	ID              string `json:"id"`
 	LedgerAccountID string `json:"ledger_account_id"`
-	Price           string `json:"price"`
+	Debit           string `json:"debit"`
+	Credit          string `json:"credit"`
 }
 
 // Document represents a Moneybird document (receipt/invoice)
 type Document struct {
-	ID      string           `json:"id"`
-	Details []DocumentDetail `json:"details"`
+	ID                    string                        `json:"id"`
+	Type                  string                        `json:"type,omitempty"` // endpoint the document was fetched from, e.g. "receipts"
+	Version               int64                         `json:"version"`
+	Date                  string                        `json:"date"`
+	State                 string                        `json:"state"`
+	ContactID             string                        `json:"contact_id"`
+	Contact               *Contact                      `json:"contact"`
+	Currency              string                        `json:"currency"`
+	TotalPriceInclTax     string                        `json:"total_price_incl_tax"`
+	TotalPriceInclTaxBase string                        `json:"total_price_incl_tax_base"`
+	PricesAreInclTax      bool                          `json:"prices_are_incl_tax"`
+	Details               []DocumentDetail              `json:"details"`
+	GeneralJournalEntries []GeneralJournalDocumentEntry `json:"general_journal_document_entries,omitempty"`
+	UpdatedAt             time.Time                     `json:"updated_at"`
 }
 
 // Client is the Moneybird API client
 type Client struct {
 	apiToken string
+	apiURL   string // the administration's API root
 	client   *http.Client
+	limiter  *rateLimiter
+	workers  int           // maximum number of concurrent requests
+	inFlight chan struct{} // holds a slot per request in flight, up to workers
 }
 
 // NewClient creates a new Moneybird API client
 func NewClient(apiToken string) *Client {
 	return &Client{
 		apiToken: apiToken,
+		apiURL:   baseURL + "/" + administrationID,
 		client:   &http.Client{Timeout: 10 * time.Second},
+		limiter:  newRateLimiter(rateLimitPerSecond, rateLimitBurst),
+		workers:  defaultWorkers,
+		inFlight: make(chan struct{}, defaultWorkers),
 	}
 }
 
-// doRequest performs an authenticated API request
-func (c *Client) doRequest(method, endpoint string) ([]byte, error) {
-	url := fmt.Sprintf("%s/%s/%s", baseURL, administrationID, endpoint)
+// SetWorkers limits the number of requests made concurrently. All workers
+// share the client's rate limiter. The limit holds for the client as a whole,
+// also when concurrent fetches fan out further. Call it before making requests.
+func (c *Client) SetWorkers(n int) {
+	c.workers = max(1, n)
+	c.inFlight = make(chan struct{}, c.workers)
+}
 
-	req, err := http.NewRequest(method, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+// doRequest performs an authenticated API request. A non-nil payload is sent
+// as the JSON request body. When Moneybird answers 429 Too Many Requests the
+// request is retried after the time it asks for, up to rateLimitRetries times.
+func (c *Client) doRequest(method, endpoint string, payload interface{}) ([]byte, error) {
+	url := fmt.Sprintf("%s/%s", c.apiURL, endpoint)
+
+	var jsonData []byte
+	if payload != nil {
+		var err error
+		if jsonData, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("marshaling request: %w", err)
+		}
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.apiToken)
-	req.Header.Set("Content-Type", "application/json")
+	c.inFlight <- struct{}{}
+	defer func() { <-c.inFlight }()
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
-	}
-	defer resp.Body.Close()
+	for attempt := 0; ; attempt++ {
+		var reqBody io.Reader
+		if jsonData != nil {
+			reqBody = bytes.NewReader(jsonData)
+		}
+		req, err := http.NewRequest(method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
-	}
+		req.Header.Set("Authorization", "Bearer "+c.apiToken)
+		req.Header.Set("Content-Type", "application/json")
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-	}
+		c.limiter.wait()
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("executing request: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
 
-	return body, nil
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < rateLimitRetries {
+			time.Sleep(retryAfter(resp.Header.Get("Retry-After")))
+			continue
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+			return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		}
+
+		return body, nil
+	}
 }
 
 // GetLedgerAccounts fetches all ledger accounts
 func (c *Client) GetLedgerAccounts() ([]LedgerAccount, error) {
-	body, err := c.doRequest("GET", "ledger_accounts.json")
+	body, err := c.doRequest("GET", "ledger_accounts.json", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -168,7 +251,7 @@ func (c *Client) GetLedgerAccounts() ([]LedgerAccount, error) {
 // GetFinancialMutations fetches financial mutations for a specific period
 func (c *Client) GetFinancialMutations(startDate, endDate string) ([]FinancialMutation, error) {
 	endpoint := fmt.Sprintf("financial_mutations.json?filter=period:%s..%s", startDate, endDate)
-	body, err := c.doRequest("GET", endpoint)
+	body, err := c.doRequest("GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -181,53 +264,125 @@ func (c *Client) GetFinancialMutations(startDate, endDate string) ([]FinancialMu
 	return mutations, nil
 }
 
-// GetDocumentsBatch fetches multiple documents at once using the synchronization endpoint
-func (c *Client) GetDocumentsBatch(documentIDs []string, docType string) ([]Document, error) {
-	if len(documentIDs) == 0 {
-		return nil, nil
+// GetFinancialMutation fetches a single financial mutation by ID
+func (c *Client) GetFinancialMutation(id string) (*FinancialMutation, error) {
+	body, err := c.doRequest("GET", fmt.Sprintf("financial_mutations/%s.json", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var mutation FinancialMutation
+	if err := json.Unmarshal(body, &mutation); err != nil {
+		return nil, fmt.Errorf("unmarshaling response: %w", err)
 	}
 
-	endpoint := fmt.Sprintf("documents/%s/synchronization.json", docType)
+	return &mutation, nil
+}
+
+// BookOnLedgerAccount books (part of) a financial mutation on a ledger account,
+// creating a LedgerAccountBooking. The price is in the administration's base
+// currency and carries the same sign as the mutation amount.
+func (c *Client) BookOnLedgerAccount(mutationID, ledgerAccountID, price, description string) (*FinancialMutation, error) {
+	endpoint := fmt.Sprintf("financial_mutations/%s/link_booking.json", mutationID)
 
 	requestBody := map[string]interface{}{
-		"ids": documentIDs,
+		"booking_type": "LedgerAccount",
+		"booking_id":   ledgerAccountID,
+		"price_base":   price,
+	}
+	if description != "" {
+		requestBody["description"] = description
 	}
 
-	jsonData, err := json.Marshal(requestBody)
+	body, err := c.doRequest("PATCH", endpoint, requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
+		return nil, err
 	}
 
-	url := fmt.Sprintf("%s/%s/%s", baseURL, administrationID, endpoint)
-	req, err := http.NewRequest("POST", url, strings.NewReader(string(jsonData)))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+	var mutation FinancialMutation
+	if err := json.Unmarshal(body, &mutation); err != nil {
+		return nil, fmt.Errorf("unmarshaling response: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.apiToken)
-	req.Header.Set("Content-Type", "application/json")
+	return &mutation, nil
+}
 
-	resp, err := c.client.Do(req)
+// GetDocumentsBatch fetches multiple documents at once using the synchronization endpoint
+func (c *Client) GetDocumentsBatch(documentIDs []string, docType string) ([]Document, error) {
+	docs, err := fetchByIDs[Document](c, "documents/"+docType, documentIDs)
 	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
+		return nil, err
+	}
+	for i := range docs {
+		docs[i].Type = docType
 	}
-	defer resp.Body.Close()
+	return docs, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// GetSalesInvoicesBatch fetches sales invoices by ID. Their details have the
+// same shape as a document's, so they are returned as documents.
+func (c *Client) GetSalesInvoicesBatch(invoiceIDs []string) ([]Document, error) {
+	invoices, err := fetchByIDs[Document](c, "sales_invoices", invoiceIDs)
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, err
+	}
+	for i := range invoices {
+		invoices[i].Type = "sales_invoices"
 	}
+	return invoices, nil
+}
+
+// fetchMutationsInChunks fetches all financial mutations in [start, end] in
+// 7-day chunks, since the list endpoint only returns a limited number of records
+func fetchMutationsInChunks(client *Client, start, end time.Time) ([]FinancialMutation, error) {
+	type chunk struct{ start, end string }
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	var chunks []chunk
+	for currentStart := start; !currentStart.After(end); {
+		chunkEnd := currentStart.AddDate(0, 0, 6)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		chunks = append(chunks, chunk{currentStart.Format("2006-01-02"), chunkEnd.Format("2006-01-02")})
+		currentStart = chunkEnd.AddDate(0, 0, 1)
 	}
 
-	var docs []Document
-	if err := json.Unmarshal(body, &docs); err != nil {
-		return nil, fmt.Errorf("unmarshaling response: %w", err)
+	// Chunks complete in any order; each prints a full line when done and the
+	// results are merged in chunk order afterwards
+	results := make([][]FinancialMutation, len(chunks))
+	var printMu sync.Mutex
+	err := forEachConcurrent(len(chunks), client.workers, func(i int) error {
+		mutations, err := client.GetFinancialMutations(chunks[i].start, chunks[i].end)
+
+		printMu.Lock()
+		defer printMu.Unlock()
+		if err != nil {
+			fmt.Printf("   Chunk %d/%d: %s to %s... failed\n", i+1, len(chunks), chunks[i].start, chunks[i].end)
+			return fmt.Errorf("fetching chunk %d: %w", i+1, err)
+		}
+		fmt.Printf("   Chunk %d/%d: %s to %s... %d transactions\n", i+1, len(chunks), chunks[i].start, chunks[i].end, len(mutations))
+		results[i] = mutations
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return docs, nil
+	// Mutations on a chunk boundary may be returned twice
+	var allMutations []FinancialMutation
+	seen := make(map[string]bool)
+	for _, mutations := range results {
+		for _, mut := range mutations {
+			if seen[mut.ID] {
+				continue
+			}
+			seen[mut.ID] = true
+			allMutations = append(allMutations, mut)
+		}
+	}
+	sortMutations(allMutations)
+
+	return allMutations, nil
 }
 
 // loadEnvFile loads environment variables from a file (for local development)
@@ -254,66 +409,8 @@ func loadEnvFile(filename string) {
 	}
 }
 
-// sendToTelegram sends a message and image to Telegram
-func sendToTelegram(botToken, chatID, message, imagePath string) error {
-	// First send the image
-	file, err := os.Open(imagePath)
-	if err != nil {
-		return fmt.Errorf("opening image: %w", err)
-	}
-	defer file.Close()
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// Add chat_id
-	_ = writer.WriteField("chat_id", chatID)
-
-	// Add caption with message
-	_ = writer.WriteField("caption", message)
-	_ = writer.WriteField("parse_mode", "HTML")
-
-	// Add photo
-	part, err := writer.CreateFormFile("photo", imagePath)
-	if err != nil {
-		return fmt.Errorf("creating form file: %w", err)
-	}
-	_, err = io.Copy(part, file)
-	if err != nil {
-		return fmt.Errorf("copying file: %w", err)
-	}
-
-	writer.Close()
-
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendPhoto", botToken)
-	req, err := http.NewRequest("POST", url, body)
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
-	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("sending request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("telegram API error (status %d): %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	return nil
-}
-
-func main() {
-	// Command-line flags
-	manualRevenue := flag.Float64("revenue", 0, "Manual revenue override (e.g., -revenue=12850.20)")
-	flag.Parse()
-
-	loadEnvFile(".env")
-
+// newClientFromEnv creates a Moneybird client from MONEYBIRD_API_TOKEN, exiting if it isn't set
+func newClientFromEnv() *Client {
 	apiToken := os.Getenv("MONEYBIRD_API_TOKEN")
 	if apiToken == "" {
 		fmt.Println("Error: MONEYBIRD_API_TOKEN environment variable not set")
@@ -321,426 +418,72 @@ func main() {
 		os.Exit(1)
 	}
 
-	client := NewClient(apiToken)
-
-	// Get current month's date range
-	now := time.Now()
-	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
-	monthEnd := now // Use today as the end date
-
-	// Get previous month's date range
-	// now := time.Now()
-	// firstOfThisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
-	// monthStart := firstOfThisMonth.AddDate(0, -1, 0)
-	// monthEnd := firstOfThisMonth.AddDate(0, 0, -1)
-
-	fmt.Printf("Fetching financial data for %s...\n\n", monthStart.Format("January 2006"))
-
-	// Fetch ledger accounts
-	fmt.Println("1. Fetching ledger accounts...")
-	accounts, err := client.GetLedgerAccounts()
-	if err != nil {
-		fmt.Printf("Error fetching accounts: %v\n", err)
-		os.Exit(1)
-	}
-	fmt.Printf("   Found %d ledger accounts\n", len(accounts))
-
-	// Create account lookup map
-	accountMap := make(map[string]LedgerAccount)
-	for _, acc := range accounts {
-		accountMap[acc.ID] = acc
-	}
-
-	// Fetch financial mutations in 7-day chunks
-	fmt.Printf("\n2. Fetching transactions in chunks...\n")
-	var allMutations []FinancialMutation
-
-	currentStart := monthStart
-	chunkNum := 1
-	for currentStart.Before(monthEnd) || currentStart.Equal(monthEnd) {
-		chunkEnd := currentStart.AddDate(0, 0, 6)
-		if chunkEnd.After(monthEnd) {
-			chunkEnd = monthEnd
-		}
-
-		fmt.Printf("   Chunk %d: %s to %s...",
-			chunkNum,
-			currentStart.Format("2006-01-02"),
-			chunkEnd.Format("2006-01-02"))
-
-		mutations, err := client.GetFinancialMutations(
-			currentStart.Format("2006-01-02"),
-			chunkEnd.Format("2006-01-02"),
-		)
-		if err != nil {
-			fmt.Printf("\n   Error fetching chunk: %v\n", err)
-			os.Exit(1)
-		}
-
-		fmt.Printf(" %d transactions\n", len(mutations))
-		allMutations = append(allMutations, mutations...)
-
-		currentStart = chunkEnd.AddDate(0, 0, 1)
-		chunkNum++
-	}
-
-	fmt.Printf("   Total: %d transactions\n", len(allMutations))
-
-	// Aggregate by ledger account
-	fmt.Println("\n3. Aggregating transactions by category...")
-	totals := make(map[string]float64)
-
-	// Find the Omzet (revenue) account ID
-	var omzetAccountID string
-	for _, acc := range accounts {
-		if acc.Name == "Omzet" && acc.AccountType == "revenue" {
-			omzetAccountID = acc.ID
-			break
-		}
-	}
-
-	// First pass: collect all unique document IDs
-	fmt.Println("   Collecting document IDs...")
-	uniqueDocIDs := make(map[string]bool)
-	for _, mut := range allMutations {
-		for _, payment := range mut.Payments {
-			if payment.InvoiceType == "Document" {
-				uniqueDocIDs[payment.InvoiceID] = true
-			}
-		}
-	}
-
-	// Fetch documents in batches
-	documentCache := make(map[string][]DocumentDetail)
-	if len(uniqueDocIDs) > 0 {
-		docIDs := make([]string, 0, len(uniqueDocIDs))
-		for id := range uniqueDocIDs {
-			docIDs = append(docIDs, id)
-		}
-
-		fmt.Printf("   Fetching %d unique documents...\n", len(docIDs))
-
-		// Try purchase_invoices first
-		purchaseDocs, err := client.GetDocumentsBatch(docIDs, "purchase_invoices")
-		if err == nil {
-			fmt.Printf("   Found %d purchase invoices\n", len(purchaseDocs))
-			for _, doc := range purchaseDocs {
-				if len(doc.Details) > 0 {
-					documentCache[doc.ID] = doc.Details
-				}
-			}
-		}
-
-		// Try receipts for any remaining
-		receiptDocs, err := client.GetDocumentsBatch(docIDs, "receipts")
-		if err == nil {
-			fmt.Printf("   Found %d receipts\n", len(receiptDocs))
-			for _, doc := range receiptDocs {
-				if len(doc.Details) > 0 {
-					documentCache[doc.ID] = doc.Details
-				}
-			}
-		}
-
-		fmt.Printf("   Successfully mapped %d/%d documents\n", len(documentCache), len(docIDs))
-	}
-
-	var paymentsProcessed, bookingsProcessed int
-
-	for _, mut := range allMutations {
-		// Process ledger account bookings (direct categorizations)
-		for _, booking := range mut.LedgerAccountBookings {
-			var amount float64
-			fmt.Sscanf(booking.Price, "%f", &amount)
-			totals[booking.LedgerAccountID] += amount
-			bookingsProcessed++
-		}
-
-		// Process payments (linked to documents/invoices)
-		for _, payment := range mut.Payments {
-			var amount float64
-			fmt.Sscanf(payment.Price, "%f", &amount)
-
-			if payment.InvoiceType == "SalesInvoice" {
-				// Sales invoices are revenue
-				totals[omzetAccountID] += amount
-				paymentsProcessed++
-			} else if payment.InvoiceType == "Document" {
-				// Look up document details in our cache
-				if details, ok := documentCache[payment.InvoiceID]; ok {
-					// Add each detail to its respective ledger account
-					for _, detail := range details {
-						if detail.LedgerAccountID != "" {
-							var detailAmount float64
-							fmt.Sscanf(detail.Price, "%f", &detailAmount)
-							// Payment prices are not negative like booking prices
-							// (-= instead of +=)
-							totals[detail.LedgerAccountID] -= detailAmount
-						}
-					}
-					paymentsProcessed++
-				}
-				// Skip if document not found
-			} else if payment.LedgerAccountID != "" {
-				// Other payment types use their ledger account
-				totals[payment.LedgerAccountID] += amount
-				paymentsProcessed++
-			}
-		}
-	}
-
-	fmt.Printf("   Processed %d bookings and %d payments\n", bookingsProcessed, paymentsProcessed)
-	fmt.Printf("   Aggregated into %d categories\n", len(totals))
-
-	// Group by account type
-	fmt.Println("\n=== Monthly Summary ===")
-	typeGroups := make(map[string]map[string]float64)
-	for ledgerID, total := range totals {
-		if acc, ok := accountMap[ledgerID]; ok {
-			if typeGroups[acc.AccountType] == nil {
-				typeGroups[acc.AccountType] = make(map[string]float64)
-			}
-			typeGroups[acc.AccountType][acc.Name] = total
-		}
-	}
-
-	// For equity accounts, group by root categories
-	fmt.Println("\nFamily Expenses (by root category):")
-	var totalFamilyExpenses float64
-	if _, ok := typeGroups["equity"]; ok {
-		// Build root category totals
-		rootTotals := make(map[string]float64)
-
-		for ledgerID, total := range totals {
-			if acc, ok := accountMap[ledgerID]; ok && acc.AccountType == "equity" {
-				totalFamilyExpenses += total
-
-				// Find the root account
-				rootAcc := acc
-
-				// Walk up the parent chain to find root
-				for rootAcc.ParentID != nil && *rootAcc.ParentID != "" {
-					if parent, exists := accountMap[*rootAcc.ParentID]; exists {
-						rootAcc = parent
-					} else {
-						break
-					}
-				}
-
-				// Add to root total
-				rootTotals[rootAcc.Name] += total
-			}
-		}
-
-		// Print sorted by amount
-		for name, amount := range rootTotals {
-			fmt.Printf("   %s: €%.2f\n", name, amount)
-		}
-		fmt.Printf("   TOTAL: €%.2f\n", totalFamilyExpenses)
-	}
-
-	// Print detailed equity for reference
-	if equityAccounts, ok := typeGroups["equity"]; ok {
-		fmt.Println("\nFamily Expenses (detailed):")
-		var totalEquity float64
-		for name, amount := range equityAccounts {
-			fmt.Printf("   %s: €%.2f\n", name, amount)
-			totalEquity += amount
-		}
-		fmt.Printf("   TOTAL: €%.2f\n", totalEquity)
-	}
-
-	// Print revenue
-	var totalRevenue float64
-	if revenueAccounts, ok := typeGroups["revenue"]; ok {
-		fmt.Println("\nRevenue:")
-		for name, amount := range revenueAccounts {
-			fmt.Printf("   %s: €%.2f\n", name, amount)
-			totalRevenue += amount
-		}
-		fmt.Printf("   TOTAL: €%.2f\n", totalRevenue)
-	}
-
-	// Print business expenses
-	var totalBusinessExpenses float64
-	if expenseAccounts, ok := typeGroups["expenses"]; ok {
-		fmt.Println("\nBusiness Expenses:")
-		for name, amount := range expenseAccounts {
-			fmt.Printf("   %s: €%.2f\n", name, amount)
-			totalBusinessExpenses += amount
-		}
-		fmt.Printf("   TOTAL: €%.2f\n", totalBusinessExpenses)
-	}
-
-	// Calculate family budget
-	fmt.Println("\n=== Family Budget Calculation ===")
-
-	// Use manual revenue if provided, otherwise use calculated
-	if *manualRevenue > 0 {
-		totalRevenue = *manualRevenue
-		fmt.Printf("Using manual revenue: €%.2f\n", totalRevenue)
-	}
+	return NewClient(apiToken)
+}
 
-	// Calculate budget from revenue
-	vatRate := 0.21
-	incomeTaxRate := 0.30
-
-	revenueExclVAT := totalRevenue / (1 + vatRate)
-	vatAmount := totalRevenue - revenueExclVAT
-	incomeTax := revenueExclVAT * incomeTaxRate
-	familyBudget := revenueExclVAT - incomeTax + totalBusinessExpenses // business expenses are negative
-
-	fmt.Printf("Gross Revenue: €%.2f\n", totalRevenue)
-	fmt.Printf("VAT (21%%): €%.2f\n", -vatAmount)
-	fmt.Printf("Revenue excl. VAT: €%.2f\n", revenueExclVAT)
-	fmt.Printf("Income Tax (30%%): €%.2f\n", -incomeTax)
-	fmt.Printf("Business Expenses: €%.2f\n", totalBusinessExpenses)
-	fmt.Printf("\n💰 Available Family Budget: €%.2f\n", familyBudget)
-
-	remaining := familyBudget + totalFamilyExpenses // expenses are negative
-	percentageUsed := (totalFamilyExpenses / familyBudget) * 100
-
-	fmt.Printf("\n💸 Family Spending: €%.2f\n", totalFamilyExpenses)
-	fmt.Printf("📊 Budget Used: %.1f%%\n", -percentageUsed)
-	fmt.Printf("💵 Remaining: €%.2f\n", remaining)
-
-	// Generate pie chart
-	fmt.Println("\n4. Generating pie chart...")
-
-	// Calculate root category totals
-	rootTotals := make(map[string]float64)
-	if _, ok := typeGroups["equity"]; ok {
-		for ledgerID, total := range totals {
-			if acc, ok := accountMap[ledgerID]; ok && acc.AccountType == "equity" {
-				rootAcc := acc
-				for rootAcc.ParentID != nil && *rootAcc.ParentID != "" {
-					if parent, exists := accountMap[*rootAcc.ParentID]; exists {
-						rootAcc = parent
-					} else {
-						break
-					}
-				}
-				rootTotals[rootAcc.Name] += total
-			}
-		}
-	}
+func main() {
+	loadEnvFile(".env")
 
-	// Sort categories by amount (largest to smallest)
-	type categoryAmount struct {
-		name   string
-		amount float64
+	// The first argument selects a command; without one (or with only flags)
+	// the monthly report runs, as it always has
+	command, args := "report", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
-	var sortedCategories []categoryAmount
-	for name, amount := range rootTotals {
-		sortedCategories = append(sortedCategories, categoryAmount{name, amount})
-	}
-	sort.Slice(sortedCategories, func(i, j int) bool {
-		return sortedCategories[i].amount < sortedCategories[j].amount // ascending (most negative first)
-	})
 
-	// Prepare data for pie chart
-	var pieValues []chart.Value
-	colors := []drawing.Color{
-		drawing.Color{R: 255, G: 99, B: 132, A: 255},  // Red
-		drawing.Color{R: 54, G: 162, B: 235, A: 255},  // Blue
-		drawing.Color{R: 255, G: 206, B: 86, A: 255},  // Yellow
-		drawing.Color{R: 75, G: 192, B: 192, A: 255},  // Teal
-		drawing.Color{R: 153, G: 102, B: 255, A: 255}, // Purple
-		drawing.Color{R: 255, G: 159, B: 64, A: 255},  // Orange
-		drawing.Color{R: 46, G: 204, B: 113, A: 255},  // Green
-	}
+	switch command {
+	case "report":
+		runReport(args)
 
-	// Add sorted categories to pie chart
-	for i, cat := range sortedCategories {
-		pieValues = append(pieValues, chart.Value{
-			Label: cat.name,
-			Value: -cat.amount, // Make positive for chart
-			Style: chart.Style{
-				FillColor: colors[i%len(colors)],
-			},
-		})
-	}
+	case "diff":
+		runDiff(args)
 
-	// Add remaining budget or over-budget indicator
-	if remaining > 0 {
-		pieValues = append(pieValues, chart.Value{
-			Label: "Remaining Budget",
-			Value: remaining,
-			Style: chart.Style{
-				FillColor: drawing.Color{R: 200, G: 200, B: 200, A: 255}, // Gray
-			},
-		})
-	} else if remaining < 0 {
-		pieValues = append(pieValues, chart.Value{
-			Label: "Over Budget",
-			Value: -remaining, // Make positive for display
-			Style: chart.Style{
-				FillColor: drawing.Color{R: 220, G: 53, B: 69, A: 255}, // Red
-			},
-		})
-	}
+	case "top":
+		runTop(args)
 
-	pie := chart.PieChart{
-		Width:  800,
-		Height: 600,
-		Values: pieValues,
-	}
+	case "accept":
+		runAccept(args)
 
-	chartFilename := fmt.Sprintf("budget_chart_%s.png", monthStart.Format("2006-01"))
-	chartFile, err := os.Create(chartFilename)
-	if err != nil {
-		fmt.Printf("   Error creating chart file: %v\n", err)
-	} else {
-		defer chartFile.Close()
-		err = pie.Render(chart.PNG, chartFile)
-		if err != nil {
-			fmt.Printf("   Error rendering chart: %v\n", err)
-		} else {
-			fmt.Printf("   ✓ Pie chart saved to %s\n", chartFilename)
-		}
-	}
+	case "split":
+		runSplit(args)
 
-	// Send to Telegram
-	telegramToken := os.Getenv("TELEGRAM_BOT_TOKEN")
-	telegramChatID := os.Getenv("TELEGRAM_CHAT_ID")
+	case "projects":
+		runProjects(args)
 
-	if telegramToken != "" && telegramChatID != "" {
-		fmt.Println("\n5. Sending to Telegram...")
+	case "cashflow":
+		runCashFlow(args)
 
-		message := fmt.Sprintf("<b>💰 Budget Overview - %s</b>\n\n", monthStart.Format("January 2006"))
-		message += fmt.Sprintf("Available Budget: €%.2f\n", familyBudget)
-		message += fmt.Sprintf("Family Spending: €%.2f\n", totalFamilyExpenses)
-		message += fmt.Sprintf("Budget Used: %.1f%%\n", -percentageUsed)
-		message += fmt.Sprintf("Remaining: €%.2f\n\n", remaining)
+	case "networth":
+		runNetWorth(args)
 
-		message += "<b>Expenses by Category:</b>\n"
-		for _, cat := range sortedCategories {
-			message += fmt.Sprintf("• %s: €%.2f\n", cat.name, -cat.amount)
-		}
+	case "year":
+		runYear(args)
 
-		err = sendToTelegram(telegramToken, telegramChatID, message, chartFilename)
-		if err != nil {
-			fmt.Printf("   Error sending to Telegram: %v\n", err)
-		} else {
-			fmt.Println("   ✓ Sent to Telegram successfully!")
+	case "bot":
+		telegramToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+		telegramChatID := os.Getenv("TELEGRAM_CHAT_ID")
+		if telegramToken == "" || telegramChatID == "" {
+			fmt.Println("Error: TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID must be set to run the bot")
+			os.Exit(1)
 		}
-	} else {
-		fmt.Println("\n⚠️  Telegram credentials not set (TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID)")
-	}
-
-	// Save detailed data
-	detailedData := map[string]interface{}{
-		"period_start": monthStart.Format("2006-01-02"),
-		"period_end":   monthEnd.Format("2006-01-02"),
-		"mutations":    allMutations,
-		"totals":       typeGroups,
-	}
-
-	detailedJSON, _ := json.MarshalIndent(detailedData, "", "  ")
-	filename := fmt.Sprintf("financial_data_%s.json", monthStart.Format("2006-01"))
-	if err := os.WriteFile(filename, detailedJSON, 0644); err != nil {
-		fmt.Printf("\nWarning: Could not save detailed JSON: %v\n", err)
-	} else {
-		fmt.Printf("\nDetailed data saved to %s\n", filename)
+		fs := flag.NewFlagSet("bot", flag.ExitOnError)
+		storeFile := fs.String("store", defaultStoreFile, "Local store used by commands such as /top")
+		fs.Parse(args)
+		runBot(newClientFromEnv(), NewTelegramBot(telegramToken, telegramChatID), *storeFile)
+
+	default:
+		fmt.Printf("Unknown command %q\n", command)
+		fmt.Println("Usage: financial-tracker [report] [flags]")
+		fmt.Println("       financial-tracker report --from-snapshot financial_data_YYYY-MM.json")
+		fmt.Println("       financial-tracker diff OLD.json NEW.json | --store")
+		fmt.Println("       financial-tracker top [--period YYYY-MM] [--json]")
+		fmt.Println("       financial-tracker accept [--min-confidence 0.9] [--yes]")
+		fmt.Println("       financial-tracker split [--splits splits.json] [--yes]")
+		fmt.Println("       financial-tracker projects [--from YYYY-MM] [--to YYYY-MM] [--project NAME]")
+		fmt.Println("       financial-tracker cashflow [--period YYYY-MM] [--balances balances.json]")
+		fmt.Println("       financial-tracker networth [--months N] | set --name NAME --kind asset|liability --value N | remove --name NAME")
+		fmt.Println("       financial-tracker year [--year YYYY] [--snapshots DIR] [--splits FILE] [--rules FILE] [--csv FILE] [--html FILE] [--chart FILE]")
+		fmt.Println("       financial-tracker bot [--store FILE]")
+		os.Exit(1)
 	}
 }
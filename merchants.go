@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const defaultTopMerchants = 10
+
+// merchantNamePrefixes are payment processor prefixes in front of the real
+// merchant name, e.g. "CCV*Bakkerij Jansen"
+var merchantNamePrefixes = []string{"ccv*", "sumup *", "sumup*", "zettle_*", "zettle *", "sq *", "pay.nl*", "bck*", "mollie*"}
+
+// merchantNameNoise are words that don't tell merchants apart
+var merchantNameNoise = map[string]bool{
+	"bv": true, "nv": true, "vof": true, "b": true, "v": true, "n": true,
+	"ltd": true, "inc": true, "gmbh": true, "via": true,
+}
+
+// MerchantStats is the spending at a single merchant in a period
+type MerchantStats struct {
+	Name          string   `json:"name"`
+	ContactID     string   `json:"contact_id,omitempty"`
+	IBANs         []string `json:"ibans,omitempty"`
+	Aliases       []string `json:"aliases,omitempty"` // counterparty names grouped under this merchant
+	Spend         float64  `json:"spend"`
+	Transactions  int      `json:"transactions"`
+	AverageTicket float64  `json:"average_ticket"`
+}
+
+// normalizeIBAN strips spaces and uppercases an account number
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+}
+
+// normalizeMerchantName reduces a counterparty name to the words that identify
+// the merchant: lowercase, without payment processor prefixes, store numbers,
+// punctuation and legal forms
+func normalizeMerchantName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, prefix := range merchantNamePrefixes {
+		name = strings.TrimPrefix(name, prefix)
+	}
+
+	// Dots are dropped so "bol.com" stays one word, other punctuation and
+	// digits separate words
+	name = strings.ReplaceAll(name, ".", "")
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	var kept []string
+	for _, word := range words {
+		if !merchantNameNoise[word] {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// merchantGroups is a union-find over merchant keys ("contact:ID", "iban:NL..",
+// "name:albert heijn"); keys end up in the same set when one transaction or
+// contact links them
+type merchantGroups map[string]string
+
+func (g merchantGroups) find(key string) string {
+	if _, ok := g[key]; !ok {
+		g[key] = key
+	}
+	for g[key] != key {
+		g[key] = g[g[key]]
+		key = g[key]
+	}
+	return key
+}
+
+func (g merchantGroups) union(keys ...string) {
+	for _, key := range keys[1:] {
+		a, b := g.find(keys[0]), g.find(key)
+		if a != b {
+			// Keep the smallest key as root so grouping is deterministic
+			if b < a {
+				a, b = b, a
+			}
+			g[b] = a
+		}
+	}
+}
+
+// mutationContact returns the contact a spending mutation was paid to: the
+// contact of its document, or the contact with the counterparty's IBAN
+func mutationContact(mut FinancialMutation, snap *Snapshot, contactsByIBAN map[string]Contact) (Contact, bool) {
+	for _, payment := range mut.Payments {
+		if doc, ok := snap.Documents[payment.InvoiceID]; ok && payment.InvoiceType == "Document" {
+			if doc.Contact != nil {
+				contact := *doc.Contact
+				if contact.ID == "" {
+					contact.ID = doc.ContactID
+				}
+				return contact, true
+			}
+		}
+	}
+	if iban := normalizeIBAN(mut.ContraAccountNumber); iban != "" {
+		contact, ok := contactsByIBAN[iban]
+		return contact, ok
+	}
+	return Contact{}, false
+}
+
+// merchantAnalytics groups the outgoing mutations of a snapshot by merchant and
+// returns the merchants sorted by spend, highest first. Merchants are matched
+// on Moneybird contact first, then IBAN when the names start alike, then a
+// normalized counterparty name where names that extend another name (e.g.
+// "jumbo utrecht" and "jumbo") are considered the same merchant.
+func merchantAnalytics(snap *Snapshot) []MerchantStats {
+	contactsByIBAN := make(map[string]Contact)
+	for _, contact := range snap.Contacts {
+		if iban := normalizeIBAN(contact.SepaIBAN); iban != "" {
+			contactsByIBAN[iban] = contact
+		}
+	}
+
+	type payment struct {
+		mut     FinancialMutation
+		amount  float64
+		keys    []string
+		contact *Contact
+	}
+
+	groups := make(merchantGroups)
+	names := make(map[string]bool)
+	var payments []payment
+	for _, mut := range snap.Mutations {
+		var amount float64
+		fmt.Sscanf(mut.Amount, "%f", &amount)
+		if amount >= 0 {
+			continue
+		}
+
+		p := payment{mut: mut, amount: -amount}
+		if contact, ok := mutationContact(mut, snap, contactsByIBAN); ok {
+			p.contact = &contact
+			p.keys = append(p.keys, "contact:"+contact.ID)
+		}
+		// An IBAN only links payments whose names start with the same word:
+		// payment processors like Mollie, Adyen and PayPal collect for many
+		// merchants on one IBAN
+		name := normalizeMerchantName(mut.ContraAccountName)
+		if iban := normalizeIBAN(mut.ContraAccountNumber); iban != "" {
+			p.keys = append(p.keys, "iban:"+iban+" "+strings.SplitN(name, " ", 2)[0])
+		}
+		if name != "" {
+			p.keys = append(p.keys, "name:"+name)
+			names[name] = true
+		}
+		if len(p.keys) == 0 {
+			p.keys = append(p.keys, "name:(unknown)")
+		}
+
+		groups.union(p.keys...)
+		payments = append(payments, p)
+	}
+
+	// Fuzzy name matching: a name whose words start with another name's words
+	// belongs to the same merchant, unless the longer names are different
+	// merchants themselves ("albert" with "albert heijn" and "albert de vries")
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+	// A name sorts before the names extending it, so going backwards
+	// "albert heijn utrecht" joins "albert heijn" before "albert" is considered
+	for i := len(sortedNames) - 1; i >= 0; i-- {
+		name := sortedNames[i]
+		var longer []string
+		roots := make(map[string]bool)
+		for _, other := range sortedNames[i+1:] {
+			if strings.HasPrefix(other, name+" ") {
+				longer = append(longer, "name:"+other)
+				roots[groups.find("name:"+other)] = true
+			}
+		}
+		if len(roots) == 1 {
+			groups.union(append([]string{"name:" + name}, longer...)...)
+		}
+	}
+
+	type merchant struct {
+		stats      MerchantStats
+		aliasCount map[string]int
+		ibans      map[string]bool
+	}
+	merchants := make(map[string]*merchant)
+	for _, p := range payments {
+		root := groups.find(p.keys[0])
+		m, ok := merchants[root]
+		if !ok {
+			m = &merchant{aliasCount: make(map[string]int), ibans: make(map[string]bool)}
+			merchants[root] = m
+		}
+
+		m.stats.Spend += p.amount
+		m.stats.Transactions++
+		if p.contact != nil && m.stats.ContactID == "" {
+			m.stats.ContactID = p.contact.ID
+			m.stats.Name = p.contact.Name()
+		}
+		if name := strings.TrimSpace(p.mut.ContraAccountName); name != "" {
+			m.aliasCount[name]++
+		}
+		if iban := normalizeIBAN(p.mut.ContraAccountNumber); iban != "" {
+			m.ibans[iban] = true
+		}
+	}
+
+	var result []MerchantStats
+	for _, m := range merchants {
+		for alias := range m.aliasCount {
+			m.stats.Aliases = append(m.stats.Aliases, alias)
+		}
+		sort.Strings(m.stats.Aliases)
+		for iban := range m.ibans {
+			m.stats.IBANs = append(m.stats.IBANs, iban)
+		}
+		sort.Strings(m.stats.IBANs)
+
+		// Without a contact, the most used counterparty name is the merchant's
+		// name; on a tie the shortest, which usually lacks a store number or city
+		if m.stats.Name == "" {
+			best := 0
+			for _, alias := range m.stats.Aliases {
+				count := m.aliasCount[alias]
+				if count > best || (count == best && len(alias) < len(m.stats.Name)) {
+					m.stats.Name, best = alias, count
+				}
+			}
+		}
+		if m.stats.Name == "" {
+			m.stats.Name = "(unknown)"
+		}
+
+		m.stats.AverageTicket = m.stats.Spend / float64(m.stats.Transactions)
+		result = append(result, m.stats)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Spend != result[j].Spend {
+			return result[i].Spend > result[j].Spend
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// topMerchants returns at most n merchants
+func topMerchants(merchants []MerchantStats, n int) []MerchantStats {
+	if n > 0 && len(merchants) > n {
+		return merchants[:n]
+	}
+	return merchants
+}
+
+// printMerchants writes the merchant table to the console
+func printMerchants(merchants []MerchantStats) {
+	if len(merchants) == 0 {
+		fmt.Println("   No outgoing transactions")
+		return
+	}
+	for i, m := range merchants {
+		fmt.Printf("   %2d. %-30s €%9.2f  %3d×  avg €%8.2f\n", i+1, m.Name, m.Spend, m.Transactions, m.AverageTicket)
+	}
+}
+
+// formatMerchantsHTML formats the merchant table for Telegram
+func formatMerchantsHTML(title string, merchants []MerchantStats) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>%s</b>\n", html.EscapeString(title))
+	if len(merchants) == 0 {
+		b.WriteString("\nNo outgoing transactions")
+		return b.String()
+	}
+	for i, m := range merchants {
+		fmt.Fprintf(&b, "\n%d. %s: €%.2f (%d×, avg €%.2f)", i+1, html.EscapeString(m.Name), m.Spend, m.Transactions, m.AverageTicket)
+	}
+	return b.String()
+}
+
+// monthSnapshot returns the stored data for the month containing t
+func monthSnapshot(store *Store, t time.Time) *Snapshot {
+	monthStart := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, -1)
+	return store.Snapshot(monthStart.Format("2006-01-02"), monthEnd.Format("2006-01-02"))
+}
+
+// runTop prints the top merchants of a month from the store or a snapshot
+func runTop(args []string) {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	storeFile := fs.String("store", defaultStoreFile, "Local store of synced Moneybird data")
+	fromSnapshot := fs.String("from-snapshot", "", "Use a saved financial_data JSON snapshot instead of the store")
+	period := fs.String("period", "", "Month to analyze (YYYY-MM, default: current month)")
+	n := fs.Int("n", defaultTopMerchants, "Number of merchants to show (0 for all)")
+	asJSON := fs.Bool("json", false, "Write the merchants as JSON")
+	fs.Parse(args)
+
+	var snap *Snapshot
+	var err error
+	if *fromSnapshot != "" {
+		snap, err = loadSnapshot(*fromSnapshot)
+	} else {
+		month := time.Now()
+		if *period != "" {
+			month, err = time.Parse("2006-01", *period)
+		}
+		if err == nil {
+			var store *Store
+			if store, err = OpenStore(*storeFile); err == nil {
+				snap = monthSnapshot(store, month)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	merchants := topMerchants(merchantAnalytics(spendingSnapshot(snap, transferConfigFromEnv())), *n)
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(merchants); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Printf("Top merchants %s to %s:\n", snap.PeriodStart, snap.PeriodEnd)
+	printMerchants(merchants)
+}
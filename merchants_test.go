@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+// merchantNames returns the merchant each counterparty name was grouped under
+func merchantNames(merchants []MerchantStats) map[string]string {
+	names := make(map[string]string)
+	for _, m := range merchants {
+		for _, alias := range m.Aliases {
+			names[alias] = m.Name
+		}
+	}
+	return names
+}
+
+func TestMerchantAnalyticsKeepsPaymentProcessorsApart(t *testing.T) {
+	const mollie = "NL12 DEUT 0000 0001 23"
+	snap := &Snapshot{Mutations: []FinancialMutation{
+		{ID: "1", Amount: "-20.00", ContraAccountName: "Stichting Dierenhulp via Mollie", ContraAccountNumber: mollie},
+		{ID: "2", Amount: "-35.00", ContraAccountName: "Fietsenwinkel De Ketting", ContraAccountNumber: mollie},
+		{ID: "3", Amount: "-5.00", ContraAccountName: "Fietsenwinkel De Ketting", ContraAccountNumber: mollie},
+		{ID: "4", Amount: "-12.00", ContraAccountName: "Jumbo Utrecht", ContraAccountNumber: "NL44 RABO 0123 4567 89"},
+		{ID: "5", Amount: "-18.00", ContraAccountName: "Jumbo Amersfoort", ContraAccountNumber: "NL44 RABO 0123 4567 89"},
+	}}
+
+	merchants := merchantAnalytics(snap)
+	if len(merchants) != 3 {
+		t.Fatalf("got %d merchants, want 3: %+v", len(merchants), merchants)
+	}
+	names := merchantNames(merchants)
+	if names["Stichting Dierenhulp via Mollie"] == names["Fietsenwinkel De Ketting"] {
+		t.Errorf("merchants paid through the same processor IBAN were grouped together")
+	}
+	if names["Jumbo Utrecht"] != names["Jumbo Amersfoort"] {
+		t.Errorf("stores of one merchant sharing an IBAN were not grouped")
+	}
+}
+
+func TestMerchantAnalyticsLeavesAmbiguousNamesApart(t *testing.T) {
+	snap := &Snapshot{Mutations: []FinancialMutation{
+		{ID: "1", Amount: "-10.00", ContraAccountName: "Albert"},
+		{ID: "2", Amount: "-40.00", ContraAccountName: "Albert Heijn 1234"},
+		{ID: "3", Amount: "-30.00", ContraAccountName: "Albert Heijn Utrecht"},
+		{ID: "4", Amount: "-25.00", ContraAccountName: "Albert de Vries"},
+		{ID: "5", Amount: "-8.00", ContraAccountName: "Jumbo"},
+		{ID: "6", Amount: "-9.00", ContraAccountName: "Jumbo Utrecht"},
+	}}
+
+	names := merchantNames(merchantAnalytics(snap))
+	if names["Albert Heijn 1234"] != names["Albert Heijn Utrecht"] {
+		t.Errorf("Albert Heijn stores were not grouped: %v", names)
+	}
+	if names["Albert"] == names["Albert Heijn 1234"] || names["Albert"] == names["Albert de Vries"] {
+		t.Errorf("\"Albert\" was grouped with a longer name although two merchants extend it: %v", names)
+	}
+	if names["Albert de Vries"] == names["Albert Heijn 1234"] {
+		t.Errorf("Albert de Vries was grouped with Albert Heijn: %v", names)
+	}
+	if names["Jumbo"] != names["Jumbo Utrecht"] {
+		t.Errorf("\"Jumbo\" was not grouped with the only name extending it: %v", names)
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+const netWorthChartFile = "net_worth.png"
+
+// Kinds of manually maintained items
+const (
+	manualAsset     = "asset"
+	manualLiability = "liability"
+)
+
+// ManualValue is the value of a manual item from a month on
+type ManualValue struct {
+	Month string  `json:"month"` // YYYY-MM
+	Value float64 `json:"value"`
+}
+
+// ManualItem is an asset or liability that isn't a financial account in
+// Moneybird, e.g. a mortgage, a pension or investments. Its value is kept by
+// hand and holds until a later month gets a new value.
+type ManualItem struct {
+	Name   string        `json:"name"`
+	Kind   string        `json:"kind"`   // asset or liability
+	Values []ManualValue `json:"values"` // sorted by month
+}
+
+// valueAt returns the item's value in a month, the latest value set on or
+// before it
+func (m ManualItem) valueAt(month string) (float64, bool) {
+	var value float64
+	var ok bool
+	for _, v := range m.Values {
+		if v.Month > month {
+			break
+		}
+		value, ok = v.Value, true
+	}
+	return value, ok
+}
+
+// NetWorthMonth is the month-end net worth and what it is made of. Liabilities
+// are positive amounts that are subtracted. Accounts only holds the accounts
+// with a known balance; the others are listed in Unknown, and the total is
+// then incomplete.
+type NetWorthMonth struct {
+	Month       string             `json:"month"` // YYYY-MM
+	Date        string             `json:"date"`  // the day the balances are from
+	Accounts    map[string]float64 `json:"accounts"`
+	Unknown     []string           `json:"unknown,omitempty"` // accounts without a known balance
+	Assets      map[string]float64 `json:"assets,omitempty"`
+	Liabilities map[string]float64 `json:"liabilities,omitempty"`
+}
+
+func sumValues(values map[string]float64) float64 {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// Total is the net worth: account balances plus assets minus liabilities
+func (n NetWorthMonth) Total() float64 {
+	return sumValues(n.Accounts) + sumValues(n.Assets) - sumValues(n.Liabilities)
+}
+
+// Complete reports whether the balance of every account is known
+func (n NetWorthMonth) Complete() bool {
+	return len(n.Unknown) == 0
+}
+
+// netWorthAt works out the net worth at the end of a month from the stored
+// financial accounts and mutations and the manual items. For the current
+// month the balances are those of today.
+func netWorthAt(store *Store, month time.Time, balances map[string]KnownBalance) NetWorthMonth {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	date := first.AddDate(0, 1, -1).Format("2006-01-02")
+	if today := time.Now().Format("2006-01-02"); date > today {
+		date = today
+	}
+
+	entry := NetWorthMonth{
+		Month:       first.Format("2006-01"),
+		Date:        date,
+		Accounts:    make(map[string]float64),
+		Assets:      make(map[string]float64),
+		Liabilities: make(map[string]float64),
+	}
+
+	history := store.MutationsBetween("", date)
+	for _, account := range store.FinancialAccountList() {
+		balance, known := balanceAt(account, balances, history, store.SyncedPeriods, date)
+		switch {
+		case !known && (account.Active || balance != 0):
+			// A balance relative to the first stored mutation isn't worth anything
+			entry.Unknown = append(entry.Unknown, account.Name)
+		case account.Active || balance != 0:
+			entry.Accounts[account.Name] = balance
+		}
+	}
+	for _, item := range store.ManualItems {
+		value, ok := item.valueAt(entry.Month)
+		if !ok {
+			continue
+		}
+		if item.Kind == manualLiability {
+			entry.Liabilities[item.Name] = value
+		} else {
+			entry.Assets[item.Name] = value
+		}
+	}
+	return entry
+}
+
+// recordNetWorth works out and stores the net worth of a month
+func recordNetWorth(store *Store, month time.Time, balances map[string]KnownBalance) NetWorthMonth {
+	entry := netWorthAt(store, month, balances)
+	store.NetWorth[entry.Month] = entry
+	return entry
+}
+
+// recordMonthNetWorth records the net worth of a month and of the month
+// before it. The month before may have been recorded mid-month, and the
+// month's change is only meaningful against its month end value.
+func recordMonthNetWorth(store *Store, month time.Time, balances map[string]KnownBalance) NetWorthMonth {
+	recordNetWorth(store, month.AddDate(0, -1, 0), balances)
+	return recordNetWorth(store, month, balances)
+}
+
+// recordNetWorthHistory stores the net worth of every month from the first
+// stored mutation or manual value up to this month
+func recordNetWorthHistory(store *Store, balances map[string]KnownBalance) []NetWorthMonth {
+	first := time.Now()
+	for _, mut := range store.FinancialMutations {
+		if date, err := time.Parse("2006-01-02", mut.Date); err == nil && date.Before(first) {
+			first = date
+		}
+	}
+	for _, item := range store.ManualItems {
+		if len(item.Values) == 0 {
+			continue
+		}
+		if month, err := time.Parse("2006-01", item.Values[0].Month); err == nil && month.Before(first) {
+			first = month
+		}
+	}
+
+	var entries []NetWorthMonth
+	month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for !month.After(time.Now()) {
+		entries = append(entries, recordNetWorth(store, month, balances))
+		month = month.AddDate(0, 1, 0)
+	}
+	return entries
+}
+
+// netWorthLine describes a month's net worth and the change since the month
+// before, e.g. "€52300.00 (+€1200.00)". It is empty when not every account
+// balance is known.
+func netWorthLine(store *Store, entry NetWorthMonth) string {
+	if !entry.Complete() {
+		return ""
+	}
+	line := fmt.Sprintf("€%.2f", entry.Total())
+	month, err := time.Parse("2006-01", entry.Month)
+	if err != nil {
+		return line
+	}
+	previous, ok := store.NetWorth[month.AddDate(0, -1, 0).Format("2006-01")]
+	if !ok || !previous.Complete() {
+		return line
+	}
+	change := entry.Total() - previous.Total()
+	sign := "+"
+	if change < 0 {
+		sign, change = "-", -change
+	}
+	return fmt.Sprintf("%s (%s€%.2f)", line, sign, change)
+}
+
+// printNetWorth writes the month-by-month net worth with its composition, and
+// the breakdown of the latest month. Months missing account balances are
+// marked with an asterisk.
+func printNetWorth(entries []NetWorthMonth) {
+	fmt.Printf("   %-7s  %12s  %12s  %12s  %12s  %11s\n", "Month", "Accounts", "Assets", "Liabilities", "Net worth", "Change")
+	var incomplete bool
+	for i, entry := range entries {
+		change := ""
+		if i > 0 && entry.Complete() && entries[i-1].Complete() {
+			change = fmt.Sprintf("%+11.2f", entry.Total()-entries[i-1].Total())
+		}
+		marker := ""
+		if !entry.Complete() {
+			marker, incomplete = " *", true
+		}
+		fmt.Printf("   %-7s  €%11.2f  €%11.2f  €%11.2f  €%11.2f  %11s%s\n", entry.Month,
+			sumValues(entry.Accounts), sumValues(entry.Assets), -sumValues(entry.Liabilities), entry.Total(), change, marker)
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if incomplete {
+		fmt.Println("   * without the accounts that have no known balance, add them to balances.json")
+	}
+
+	latest := entries[len(entries)-1]
+	fmt.Printf("\nComposition on %s:\n", latest.Date)
+	for _, group := range []struct {
+		title  string
+		values map[string]float64
+		sign   float64
+	}{
+		{"Accounts", latest.Accounts, 1},
+		{"Assets", latest.Assets, 1},
+		{"Liabilities", latest.Liabilities, -1},
+	} {
+		names := make([]string, 0, len(group.values))
+		for name := range group.values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("   %-12s %-30s €%11.2f\n", group.title, name, group.sign*group.values[name])
+		}
+	}
+	for _, name := range latest.Unknown {
+		fmt.Printf("   %-12s %-30s %12s\n", "Accounts", name, "unknown")
+	}
+	fmt.Printf("   %-43s €%11.2f\n", "NET WORTH", latest.Total())
+}
+
+// renderNetWorthChart draws the net worth and its composition per month as
+// lines. At least two months are needed.
+func renderNetWorthChart(entries []NetWorthMonth, filename string) error {
+	if len(entries) < 2 {
+		return fmt.Errorf("need at least two months to chart, have %d", len(entries))
+	}
+
+	series := []struct {
+		name  string
+		color drawing.Color
+		value func(NetWorthMonth) float64
+	}{
+		{"Net worth", drawing.Color{R: 46, G: 204, B: 113, A: 255}, NetWorthMonth.Total},
+		{"Accounts", drawing.Color{R: 54, G: 162, B: 235, A: 255}, func(n NetWorthMonth) float64 { return sumValues(n.Accounts) }},
+		{"Assets", drawing.Color{R: 153, G: 102, B: 255, A: 255}, func(n NetWorthMonth) float64 { return sumValues(n.Assets) }},
+		{"Liabilities", drawing.Color{R: 255, G: 99, B: 132, A: 255}, func(n NetWorthMonth) float64 { return -sumValues(n.Liabilities) }},
+	}
+
+	graph := chart.Chart{
+		Width:  900,
+		Height: 500,
+		XAxis:  chart.XAxis{ValueFormatter: chart.TimeValueFormatterWithFormat("2006-01")},
+		YAxis: chart.YAxis{ValueFormatter: func(v interface{}) string {
+			return fmt.Sprintf("€%.0f", v.(float64))
+		}},
+	}
+	for _, s := range series {
+		line := chart.TimeSeries{
+			Name:  s.name,
+			Style: chart.Style{StrokeColor: s.color, StrokeWidth: 2},
+		}
+		for _, entry := range entries {
+			month, _ := time.Parse("2006-01", entry.Month)
+			line.XValues = append(line.XValues, month)
+			line.YValues = append(line.YValues, s.value(entry))
+		}
+		graph.Series = append(graph.Series, line)
+	}
+	// One tick per month, the automatic ticks repeat months
+	for _, entry := range entries {
+		month, _ := time.Parse("2006-01", entry.Month)
+		graph.XAxis.Ticks = append(graph.XAxis.Ticks, chart.Tick{Value: chart.TimeToFloat64(month), Label: entry.Month})
+	}
+	graph.Elements = []chart.Renderable{chart.Legend(&graph)}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("creating chart file: %w", err)
+	}
+	defer file.Close()
+	if err := graph.Render(chart.PNG, file); err != nil {
+		return fmt.Errorf("rendering chart: %w", err)
+	}
+	return nil
+}
+
+// runNetWorth records and shows the net worth per month. `networth set` and
+// `networth remove` maintain the manual assets and liabilities.
+func runNetWorth(args []string) {
+	if len(args) > 0 && (args[0] == "set" || args[0] == "remove") {
+		runNetWorthItem(args[0], args[1:])
+		return
+	}
+
+	fs := flag.NewFlagSet("networth", flag.ExitOnError)
+	storeFile := fs.String("store", defaultStoreFile, "Local store of synced Moneybird data")
+	balancesFile := fs.String("balances", defaultBalancesFile, "File with known balances by financial account ID, name or IBAN, for accounts without bank statements")
+	chartFile := fs.String("chart", netWorthChartFile, "File to draw the net worth chart in")
+	months := fs.Int("months", 0, "Only show the last months (0 for all)")
+	fs.Parse(args)
+
+	store, err := OpenStore(*storeFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	balances, err := loadKnownBalances(*balancesFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	entries := recordNetWorthHistory(store, balanceAnchors(store, balances))
+	if err := store.Save(); err != nil {
+		fmt.Printf("Warning: could not save store: %v\n", err)
+	}
+	if *months > 0 && len(entries) > *months {
+		entries = entries[len(entries)-*months:]
+	}
+
+	fmt.Println("Net worth per month:")
+	printNetWorth(entries)
+
+	if err := renderNetWorthChart(entries, *chartFile); err != nil {
+		fmt.Printf("\nNo chart: %v\n", err)
+	} else {
+		fmt.Printf("\n✓ Chart saved to %s\n", *chartFile)
+	}
+}
+
+// runNetWorthItem sets the value of a manual asset or liability from a month
+// on, or removes the item
+func runNetWorthItem(action string, args []string) {
+	fs := flag.NewFlagSet("networth "+action, flag.ExitOnError)
+	storeFile := fs.String("store", defaultStoreFile, "Local store of synced Moneybird data")
+	name := fs.String("name", "", "Name of the asset or liability, e.g. Hypotheek")
+	kind := fs.String("kind", manualAsset, "asset or liability")
+	value := fs.Float64("value", 0, "Value, positive for liabilities too")
+	month := fs.String("month", time.Now().Format("2006-01"), "Month the value holds from (YYYY-MM)")
+	fs.Parse(args)
+
+	if *name == "" {
+		fmt.Println("Error: -name is required")
+		os.Exit(1)
+	}
+	if *kind != manualAsset && *kind != manualLiability {
+		fmt.Printf("Error: -kind must be %s or %s\n", manualAsset, manualLiability)
+		os.Exit(1)
+	}
+	if _, err := time.Parse("2006-01", *month); err != nil {
+		fmt.Printf("Error: invalid month: %v\n", err)
+		os.Exit(1)
+	}
+
+	store, err := OpenStore(*storeFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	key := strings.ToLower(*name)
+	if action == "remove" {
+		if _, ok := store.ManualItems[key]; !ok {
+			fmt.Printf("Error: no asset or liability named %q\n", *name)
+			os.Exit(1)
+		}
+		delete(store.ManualItems, key)
+		fmt.Printf("✓ Removed %s\n", *name)
+	} else {
+		item := store.ManualItems[key]
+		item.Name, item.Kind = *name, *kind
+
+		var values []ManualValue
+		for _, v := range item.Values {
+			if v.Month != *month {
+				values = append(values, v)
+			}
+		}
+		values = append(values, ManualValue{Month: *month, Value: *value})
+		sort.Slice(values, func(i, j int) bool {
+			return values[i].Month < values[j].Month
+		})
+		item.Values = values
+		store.ManualItems[key] = item
+		fmt.Printf("✓ %s (%s) is €%.2f from %s on\n", *name, *kind, *value, *month)
+	}
+
+	if err := store.Save(); err != nil {
+		fmt.Printf("Error: could not save store: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNetWorthLeavesOutUnknownBalances(t *testing.T) {
+	store, err := OpenStore(t.TempDir() + "/store.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.FinancialAccounts["bank"] = FinancialAccount{ID: "bank", Name: "Betaalrekening", Active: true}
+	store.FinancialAccounts["card"] = FinancialAccount{ID: "card", Name: "Creditcard", Active: true}
+	store.FinancialMutations["m1"] = FinancialMutation{ID: "m1", FinancialAccountID: "bank", Date: "2025-02-10", Amount: "-100.00"}
+	store.FinancialMutations["m2"] = FinancialMutation{ID: "m2", FinancialAccountID: "card", Date: "2025-02-12", Amount: "-40.00"}
+	store.ManualItems["mortgage"] = ManualItem{Name: "Mortgage", Kind: manualLiability, Values: []ManualValue{{"2025-01", 200000}}}
+
+	store.SyncedPeriods = Periods{{"2025-02-01", "2025-02-28"}}
+	balances := map[string]KnownBalance{"bank": {Date: "2025-01-31", Balance: 1000}}
+	february := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	entry := recordNetWorth(store, february, balances)
+	if len(entry.Accounts) != 1 || entry.Accounts["Betaalrekening"] != 900 {
+		t.Errorf("accounts = %v, want only the bank account at 900.00", entry.Accounts)
+	}
+	if entry.Complete() || len(entry.Unknown) != 1 || entry.Unknown[0] != "Creditcard" {
+		t.Errorf("unknown = %v, want the credit card", entry.Unknown)
+	}
+	if line := netWorthLine(store, entry); line != "" {
+		t.Errorf("net worth line = %q, want none while a balance is unknown", line)
+	}
+
+	balances["card"] = KnownBalance{Date: "2025-02-28", Balance: -40}
+	entry = recordNetWorth(store, february, balances)
+	if !entry.Complete() || entry.Total() != 900-40-200000 {
+		t.Errorf("net worth = %.2f (unknown %v), want %.2f", entry.Total(), entry.Unknown, 900.0-40-200000)
+	}
+	if line := netWorthLine(store, entry); line != "€-199140.00" {
+		t.Errorf("net worth line = %q", line)
+	}
+}
+
+func TestRecordMonthNetWorthRefreshesLastMonth(t *testing.T) {
+	store, err := OpenStore(t.TempDir() + "/store.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.FinancialAccounts["bank"] = FinancialAccount{ID: "bank", Name: "Betaalrekening", Active: true}
+	store.FinancialMutations["m1"] = FinancialMutation{ID: "m1", FinancialAccountID: "bank", Date: "2025-02-20", Amount: "-300.00"}
+	store.FinancialMutations["m2"] = FinancialMutation{ID: "m2", FinancialAccountID: "bank", Date: "2025-03-05", Amount: "-100.00"}
+	store.SyncedPeriods = Periods{{"2025-02-01", "2025-03-31"}}
+	balances := map[string]KnownBalance{"bank": {Date: "2025-01-31", Balance: 1000}}
+
+	// February was recorded mid-month, before the €300 charge
+	store.NetWorth["2025-02"] = NetWorthMonth{Month: "2025-02", Date: "2025-02-14", Accounts: map[string]float64{"Betaalrekening": 1000}}
+
+	march := recordMonthNetWorth(store, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), balances)
+	if february := store.NetWorth["2025-02"]; february.Date != "2025-02-28" || february.Total() != 700 {
+		t.Errorf("February = %.2f on %s, want 700.00 at the month end", february.Total(), february.Date)
+	}
+	if line := netWorthLine(store, march); line != "€600.00 (-€100.00)" {
+		t.Errorf("net worth line = %q, want the change since February's month end", line)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"strings"
+)
+
+// SummaryLine is a single labeled figure in a report, e.g. "Remaining: €512.30"
+type SummaryLine struct {
+	Label string
+	Value string
+}
+
+// ReportCategory is a spending category with its (positive) amount
+type ReportCategory struct {
+	Name   string
+	Amount float64
+}
+
+// Report is the structured content handed to every notifier. Each backend
+// decides how to format it for its own channel.
+type Report struct {
+	Title      string
+	Alerts     []string
+	Summary    []SummaryLine
+	Categories []ReportCategory
+	Charts     []string // paths to PNG files
+}
+
+// Notifier delivers a report to a single channel
+type Notifier interface {
+	Name() string
+	Notify(report Report) error
+}
+
+// textStyle describes how a channel marks up bold text and escapes user content
+type textStyle struct {
+	bold   func(string) string
+	escape func(string) string
+}
+
+var plainStyle = textStyle{
+	bold:   func(s string) string { return s },
+	escape: func(s string) string { return s },
+}
+
+var markdownStyle = textStyle{
+	bold:   func(s string) string { return "**" + s + "**" },
+	escape: func(s string) string { return s },
+}
+
+// htmlStyle formats text as HTML, as used by Telegram and Matrix
+var htmlStyle = textStyle{
+	bold:   func(s string) string { return "<b>" + s + "</b>" },
+	escape: html.EscapeString,
+}
+
+// formatReportText renders a report as text in the given style
+func formatReportText(report Report, style textStyle) string {
+	var sb strings.Builder
+
+	if report.Title != "" {
+		sb.WriteString(style.bold(style.escape(report.Title)))
+		sb.WriteString("\n\n")
+	}
+
+	if len(report.Alerts) > 0 {
+		for _, alert := range report.Alerts {
+			fmt.Fprintf(&sb, "⚠️ %s\n", style.escape(alert))
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, line := range report.Summary {
+		fmt.Fprintf(&sb, "%s: %s\n", style.escape(line.Label), style.escape(line.Value))
+	}
+
+	if len(report.Categories) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(style.bold("Expenses by Category:"))
+		sb.WriteString("\n")
+		for _, cat := range report.Categories {
+			fmt.Fprintf(&sb, "• %s: €%.2f\n", style.escape(cat.Name), cat.Amount)
+		}
+	}
+
+	return sb.String()
+}
+
+// notifiersFromEnv returns a notifier for every channel that has its
+// environment variables set
+func notifiersFromEnv() []Notifier {
+	var notifiers []Notifier
+
+	if token, chatID := os.Getenv("TELEGRAM_BOT_TOKEN"), os.Getenv("TELEGRAM_CHAT_ID"); token != "" && chatID != "" {
+		notifiers = append(notifiers, &TelegramNotifier{bot: NewTelegramBot(token, chatID)})
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		notifiers = append(notifiers, &EmailNotifier{
+			addr:     host + ":" + port,
+			host:     host,
+			username: os.Getenv("SMTP_USERNAME"),
+			password: os.Getenv("SMTP_PASSWORD"),
+			from:     os.Getenv("SMTP_FROM"),
+			to:       splitList(os.Getenv("SMTP_TO")),
+		})
+	}
+
+	if url := os.Getenv("SLACK_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, NewSlackNotifier(url))
+	}
+
+	if url := os.Getenv("DISCORD_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, NewDiscordNotifier(url))
+	}
+
+	if homeserver := os.Getenv("MATRIX_HOMESERVER"); homeserver != "" {
+		notifiers = append(notifiers, NewMatrixNotifier(
+			homeserver,
+			os.Getenv("MATRIX_ACCESS_TOKEN"),
+			os.Getenv("MATRIX_ROOM_ID"),
+		))
+	}
+
+	if url := os.Getenv("NTFY_TOPIC_URL"); url != "" {
+		notifiers = append(notifiers, NewNtfyNotifier(url, os.Getenv("NTFY_TOKEN")))
+	}
+
+	return notifiers
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testReport returns a report with one of everything a backend formats
+func testReport(charts ...string) Report {
+	return Report{
+		Title:      "Financial Report 2024-01",
+		Alerts:     []string{"Groceries over budget"},
+		Summary:    []SummaryLine{{Label: "Remaining", Value: "€512.30"}},
+		Categories: []ReportCategory{{Name: "Food & Drinks", Amount: 123.45}},
+		Charts:     charts,
+	}
+}
+
+// writeTestChart writes a stand-in PNG and returns its path
+func writeTestChart(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("\x89PNG test chart"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
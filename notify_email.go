@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EmailNotifier sends the report as an HTML email with the charts as inline images
+type EmailNotifier struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// Name returns the channel name
+func (n *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Notify sends the report over SMTP
+func (n *EmailNotifier) Notify(report Report) error {
+	if n.from == "" || len(n.to) == 0 {
+		return fmt.Errorf("SMTP_FROM and SMTP_TO must be set")
+	}
+
+	msg, err := n.buildMessage(report)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+
+	if err := smtp.SendMail(n.addr, auth, n.from, n.to, msg); err != nil {
+		return fmt.Errorf("sending mail: %w", err)
+	}
+	return nil
+}
+
+// buildMessage assembles a multipart/related message: the HTML body followed by
+// the charts, which the body references by Content-ID
+func (n *EmailNotifier) buildMessage(report Report) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	var cids []string
+	for i := range report.Charts {
+		cids = append(cids, fmt.Sprintf("chart%d@financial-tracker", i))
+	}
+
+	htmlPart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating html part: %w", err)
+	}
+	qp := quotedprintable.NewWriter(htmlPart)
+	if _, err := qp.Write([]byte(formatReportHTML(report, cids))); err != nil {
+		return nil, fmt.Errorf("writing html part: %w", err)
+	}
+	qp.Close()
+
+	for i, chartPath := range report.Charts {
+		data, err := os.ReadFile(chartPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading chart: %w", err)
+		}
+
+		name := filepath.Base(chartPath)
+		imagePart, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("image/png; name=%q", name)},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-ID":                {"<" + cids[i] + ">"},
+			"Content-Disposition":       {fmt.Sprintf("inline; filename=%q", name)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating image part: %w", err)
+		}
+
+		// Base64 bodies must be wrapped at 76 characters
+		encoded := base64.StdEncoding.EncodeToString(data)
+		for len(encoded) > 76 {
+			fmt.Fprintf(imagePart, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(imagePart, "%s\r\n", encoded)
+	}
+
+	writer.Close()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", report.Title))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/related; boundary=%s\r\n", writer.Boundary())
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// formatReportHTML renders the report as an HTML document, embedding the images
+// with the given Content-IDs
+func formatReportHTML(report Report, imageCIDs []string) string {
+	var sb strings.Builder
+
+	sb.WriteString("<html><body style=\"font-family: sans-serif\">\n")
+	fmt.Fprintf(&sb, "<h2>%s</h2>\n", html.EscapeString(report.Title))
+
+	for _, alert := range report.Alerts {
+		fmt.Fprintf(&sb, "<p style=\"color: #dc3545\">⚠️ %s</p>\n", html.EscapeString(alert))
+	}
+
+	sb.WriteString("<table>\n")
+	for _, line := range report.Summary {
+		fmt.Fprintf(&sb, "<tr><td>%s</td><td style=\"text-align: right\"><b>%s</b></td></tr>\n",
+			html.EscapeString(line.Label), html.EscapeString(line.Value))
+	}
+	sb.WriteString("</table>\n")
+
+	if len(report.Categories) > 0 {
+		sb.WriteString("<h3>Expenses by Category</h3>\n<table>\n")
+		for _, cat := range report.Categories {
+			fmt.Fprintf(&sb, "<tr><td>%s</td><td style=\"text-align: right\">€%.2f</td></tr>\n",
+				html.EscapeString(cat.Name), cat.Amount)
+		}
+		sb.WriteString("</table>\n")
+	}
+
+	for _, cid := range imageCIDs {
+		fmt.Fprintf(&sb, "<p><img src=\"cid:%s\" alt=\"chart\"></p>\n", cid)
+	}
+
+	sb.WriteString("</body></html>\n")
+	return sb.String()
+}
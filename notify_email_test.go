@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP is a minimal SMTP server that accepts a single message. It
+// advertises AUTH PLAIN without STARTTLS, which net/smtp allows on localhost.
+type fakeSMTP struct {
+	listener   net.Listener
+	rejectRcpt bool // answer RCPT TO with 550
+
+	auth string // decoded AUTH PLAIN credentials
+	from string
+	rcpt []string
+	data string
+	done chan struct{}
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{listener: listener, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			fields := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			s.auth = string(decoded)
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.from = line
+			reply("250 OK")
+		case "RCPT":
+			if s.rejectRcpt {
+				reply("550 5.1.1 No such user")
+				continue
+			}
+			s.rcpt = append(s.rcpt, line)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.data = data.String()
+			reply("250 OK: queued")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTP) notifier() *EmailNotifier {
+	return &EmailNotifier{
+		addr:     s.listener.Addr().String(),
+		host:     "127.0.0.1",
+		username: "tracker",
+		password: "hunter2",
+		from:     "tracker@example.org",
+		to:       []string{"family@example.org", "accountant@example.org"},
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	server := newFakeSMTP(t)
+	chart := writeTestChart(t, "budget_chart.png")
+
+	if err := server.notifier().Notify(testReport(chart)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	<-server.done
+
+	if server.auth != "\x00tracker\x00hunter2" {
+		t.Errorf("AUTH PLAIN credentials = %q", server.auth)
+	}
+	if server.from != "MAIL FROM:<tracker@example.org>" && !strings.HasPrefix(server.from, "MAIL FROM:<tracker@example.org> ") {
+		t.Errorf("MAIL = %q", server.from)
+	}
+	if len(server.rcpt) != 2 {
+		t.Errorf("RCPT = %v, want both recipients", server.rcpt)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(server.data))
+	if err != nil {
+		t.Fatalf("parsing message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Financial Report 2024-01" {
+		t.Errorf("Subject = %q", subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" {
+		t.Fatalf("Content-Type = %q", msg.Header.Get("Content-Type"))
+	}
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	htmlPart, err := parts.NextPart()
+	if err != nil {
+		t.Fatalf("reading html part: %v", err)
+	}
+	body, _ := io.ReadAll(htmlPart) // multipart decodes quoted-printable
+	for _, want := range []string{"<h2>Financial Report 2024-01</h2>", "Food &amp; Drinks", `<img src="cid:chart0@financial-tracker"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("html body doesn't contain %q", want)
+		}
+	}
+
+	imagePart, err := parts.NextPart()
+	if err != nil {
+		t.Fatalf("reading image part: %v", err)
+	}
+	if cid := imagePart.Header.Get("Content-ID"); cid != "<chart0@financial-tracker>" {
+		t.Errorf("Content-ID = %q", cid)
+	}
+	encoded, _ := io.ReadAll(imagePart)
+	image, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil || string(image) != "\x89PNG test chart" {
+		t.Errorf("image = %q, %v", image, err)
+	}
+}
+
+func TestEmailNotifierRejected(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.rejectRcpt = true
+
+	err := server.notifier().Notify(testReport())
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Fatalf("Notify error = %v, want the 550 rejection", err)
+	}
+}
+
+func TestEmailNotifierMissingConfig(t *testing.T) {
+	n := &EmailNotifier{addr: "127.0.0.1:1", host: "127.0.0.1"}
+	if err := n.Notify(testReport()); err == nil {
+		t.Fatal("Notify without SMTP_FROM and SMTP_TO should fail")
+	}
+}
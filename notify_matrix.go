@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MatrixNotifier posts the report to a Matrix room using the client-server API
+type MatrixNotifier struct {
+	homeserver  string
+	accessToken string
+	roomID      string
+	client      *http.Client
+}
+
+// NewMatrixNotifier creates a notifier for a room on the given homeserver
+func NewMatrixNotifier(homeserver, accessToken, roomID string) *MatrixNotifier {
+	return &MatrixNotifier{
+		homeserver:  strings.TrimRight(homeserver, "/"),
+		accessToken: accessToken,
+		roomID:      roomID,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the channel name
+func (n *MatrixNotifier) Name() string {
+	return "Matrix"
+}
+
+// Notify sends the report as a formatted text event followed by one image event per chart
+func (n *MatrixNotifier) Notify(report Report) error {
+	if n.accessToken == "" || n.roomID == "" {
+		return fmt.Errorf("MATRIX_ACCESS_TOKEN and MATRIX_ROOM_ID must be set")
+	}
+
+	formatted := strings.ReplaceAll(formatReportText(report, htmlStyle), "\n", "<br>")
+	err := n.sendEvent(map[string]interface{}{
+		"msgtype":        "m.text",
+		"body":           formatReportText(report, plainStyle),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, chartPath := range report.Charts {
+		contentURI, size, err := n.upload(chartPath)
+		if err != nil {
+			return err
+		}
+
+		err = n.sendEvent(map[string]interface{}{
+			"msgtype": "m.image",
+			"body":    filepath.Base(chartPath),
+			"url":     contentURI,
+			"info":    map[string]interface{}{"mimetype": "image/png", "size": size},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// do performs an authenticated request against the homeserver
+func (n *MatrixNotifier) do(method, path, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, n.homeserver+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+n.accessToken)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// sendEvent sends an m.room.message event to the room
+func (n *MatrixNotifier) sendEvent(content map[string]interface{}) error {
+	jsonData, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+
+	txnID := fmt.Sprintf("ft%d", time.Now().UnixNano())
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%s", url.PathEscape(n.roomID), txnID)
+	_, err = n.do("PUT", path, "application/json", bytes.NewReader(jsonData))
+	return err
+}
+
+// upload stores a PNG in the homeserver's media repository and returns its mxc:// URI
+func (n *MatrixNotifier) upload(path string) (string, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("reading file: %w", err)
+	}
+
+	uploadPath := "/_matrix/media/v3/upload?filename=" + url.QueryEscape(filepath.Base(path))
+	body, err := n.do("POST", uploadPath, "image/png", bytes.NewReader(data))
+	if err != nil {
+		return "", 0, err
+	}
+
+	var result struct {
+		ContentURI string `json:"content_uri"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", 0, fmt.Errorf("unmarshaling response: %w", err)
+	}
+	return result.ContentURI, len(data), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMatrixNotifier(t *testing.T) {
+	chart := writeTestChart(t, "budget_chart.png")
+
+	var events []map[string]interface{}
+	var uploaded []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret-token" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/_matrix/media/v3/upload":
+			if r.URL.Query().Get("filename") != "budget_chart.png" {
+				t.Errorf("upload filename = %q", r.URL.Query().Get("filename"))
+			}
+			if ct := r.Header.Get("Content-Type"); ct != "image/png" {
+				t.Errorf("upload Content-Type = %q", ct)
+			}
+			uploaded, _ = io.ReadAll(r.Body)
+			w.Write([]byte(`{"content_uri": "mxc://example.org/abc"}`))
+		case r.Method == "PUT" && strings.HasPrefix(r.URL.EscapedPath(), "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/"):
+			var event map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&event)
+			events = append(events, event)
+			w.Write([]byte(`{"event_id": "$1"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	n := NewMatrixNotifier(server.URL+"/", "secret-token", "!room:example.org")
+	if err := n.Notify(testReport(chart)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("sent %d events, want a text and an image event", len(events))
+	}
+	text := events[0]
+	if text["msgtype"] != "m.text" || text["format"] != "org.matrix.custom.html" {
+		t.Errorf("unexpected text event %v", text)
+	}
+	if body, _ := text["body"].(string); !strings.Contains(body, "Food & Drinks: €123.45") {
+		t.Errorf("plain body %q", body)
+	}
+	if formatted, _ := text["formatted_body"].(string); !strings.Contains(formatted, "<b>Financial Report 2024-01</b><br>") ||
+		!strings.Contains(formatted, "Food &amp; Drinks") {
+		t.Errorf("formatted body %q", formatted)
+	}
+
+	image := events[1]
+	if image["msgtype"] != "m.image" || image["url"] != "mxc://example.org/abc" || image["body"] != "budget_chart.png" {
+		t.Errorf("unexpected image event %v", image)
+	}
+	if string(uploaded) != "\x89PNG test chart" {
+		t.Errorf("uploaded %q", uploaded)
+	}
+}
+
+func TestMatrixNotifierError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errcode": "M_UNKNOWN_TOKEN"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	err := NewMatrixNotifier(server.URL, "expired", "!room:example.org").Notify(testReport())
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "M_UNKNOWN_TOKEN") {
+		t.Fatalf("Notify error = %v, want the status and errcode", err)
+	}
+}
+
+func TestMatrixNotifierMissingConfig(t *testing.T) {
+	if err := NewMatrixNotifier("http://127.0.0.1:1", "", "").Notify(testReport()); err == nil {
+		t.Fatal("Notify without a token and room should fail")
+	}
+}
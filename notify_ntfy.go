@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// NtfyNotifier publishes the report to an ntfy topic, e.g. https://ntfy.sh/my-budget
+type NtfyNotifier struct {
+	topicURL string
+	token    string
+	client   *http.Client
+}
+
+// NewNtfyNotifier creates a notifier for an ntfy topic URL. The token is optional.
+func NewNtfyNotifier(topicURL, token string) *NtfyNotifier {
+	return &NtfyNotifier{
+		topicURL: topicURL,
+		token:    token,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the channel name
+func (n *NtfyNotifier) Name() string {
+	return "ntfy"
+}
+
+// Notify publishes the report as a markdown message, followed by one
+// attachment message per chart
+func (n *NtfyNotifier) Notify(report Report) error {
+	// ntfy shows the title separately, so leave it out of the body
+	body := report
+	body.Title = ""
+	text := formatReportText(body, markdownStyle)
+
+	// Header values have to be ASCII; ntfy decodes RFC 2047 encoded words
+	title := mime.BEncoding.Encode("utf-8", report.Title)
+
+	headers := map[string]string{
+		"Title":    title,
+		"Markdown": "yes",
+	}
+	if err := n.publish("POST", strings.NewReader(text), headers); err != nil {
+		return err
+	}
+
+	for _, chartPath := range report.Charts {
+		file, err := os.Open(chartPath)
+		if err != nil {
+			return fmt.Errorf("opening chart: %w", err)
+		}
+
+		err = n.publish("PUT", file, map[string]string{
+			"Title":    title,
+			"Filename": filepath.Base(chartPath),
+		})
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publish sends a single message to the topic
+func (n *NtfyNotifier) publish(method string, body io.Reader, headers map[string]string) error {
+	req, err := http.NewRequest(method, n.topicURL, body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNtfyNotifier(t *testing.T) {
+	chart := writeTestChart(t, "budget_chart.png")
+
+	type message struct {
+		method, title, filename, markdown, body string
+	}
+	var messages []message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/budget" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer tk_secret" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		title, err := new(mime.WordDecoder).DecodeHeader(r.Header.Get("Title"))
+		if err != nil {
+			t.Errorf("decoding title: %v", err)
+		}
+		body, _ := io.ReadAll(r.Body)
+		messages = append(messages, message{r.Method, title, r.Header.Get("Filename"), r.Header.Get("Markdown"), string(body)})
+		w.Write([]byte(`{"id": "abc"}`))
+	}))
+	defer server.Close()
+
+	report := testReport(chart)
+	report.Title = "Financiële rapportage"
+	if err := NewNtfyNotifier(server.URL+"/budget", "tk_secret").Notify(report); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("published %d messages, want the text and one chart", len(messages))
+	}
+	text := messages[0]
+	if text.method != "POST" || text.markdown != "yes" || text.title != "Financiële rapportage" {
+		t.Errorf("unexpected text message %+v", text)
+	}
+	if strings.Contains(text.body, "Financiële rapportage") || !strings.Contains(text.body, "• Food & Drinks: €123.45") {
+		t.Errorf("text body %q", text.body)
+	}
+	attachment := messages[1]
+	if attachment.method != "PUT" || attachment.filename != "budget_chart.png" || attachment.body != "\x89PNG test chart" {
+		t.Errorf("unexpected attachment %+v", attachment)
+	}
+}
+
+func TestNtfyNotifierWithoutToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("Authorization = %q, want none", auth)
+		}
+	}))
+	defer server.Close()
+
+	if err := NewNtfyNotifier(server.URL, "").Notify(testReport()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+}
+
+func TestNtfyNotifierError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code": 40301, "error": "forbidden"}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewNtfyNotifier(server.URL, "wrong").Notify(testReport())
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "forbidden") {
+		t.Fatalf("Notify error = %v, want the status and body", err)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxDiscordContentLength is the most characters a Discord message can hold
+const maxDiscordContentLength = 2000
+
+// postJSON posts a JSON payload and checks for a 2xx response
+func postJSON(client *http.Client, url string, payload interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// checkResponse turns a non-2xx response into an error including the body
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// SlackNotifier posts the report to a Slack incoming webhook. Incoming webhooks
+// can't carry files, so charts are left out.
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a notifier for a Slack incoming webhook URL
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the channel name
+func (n *SlackNotifier) Name() string {
+	return "Slack"
+}
+
+// slackStyle formats text as Slack mrkdwn
+var slackStyle = textStyle{
+	bold: func(s string) string { return "*" + s + "*" },
+	escape: strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+	).Replace,
+}
+
+// Notify posts the report as a mrkdwn message
+func (n *SlackNotifier) Notify(report Report) error {
+	payload := map[string]interface{}{
+		"text": formatReportText(report, slackStyle),
+	}
+	return postJSON(n.client, n.webhookURL, payload)
+}
+
+// DiscordNotifier posts the report to a Discord webhook, with every chart
+// embedded as an image
+type DiscordNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewDiscordNotifier creates a notifier for a Discord webhook URL
+func NewDiscordNotifier(webhookURL string) *DiscordNotifier {
+	return &DiscordNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the channel name
+func (n *DiscordNotifier) Name() string {
+	return "Discord"
+}
+
+// Notify posts the report as a markdown message with the charts attached.
+// Whatever doesn't fit in the first message follows in more messages.
+func (n *DiscordNotifier) Notify(report Report) error {
+	content, rest := splitMessage(formatReportText(report, markdownStyle), maxDiscordContentLength)
+	if err := n.post(content, report.Charts); err != nil {
+		return err
+	}
+	for strings.TrimSpace(rest) != "" {
+		content, rest = splitMessage(rest, maxDiscordContentLength)
+		if err := n.post(content, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// post sends a single message with the charts attached
+func (n *DiscordNotifier) post(content string, charts []string) error {
+	payload := map[string]interface{}{
+		"content": content,
+	}
+
+	if len(charts) == 0 {
+		return postJSON(n.client, n.webhookURL, payload)
+	}
+
+	var embeds []map[string]interface{}
+	for _, chartPath := range charts {
+		embeds = append(embeds, map[string]interface{}{
+			"image": map[string]string{"url": "attachment://" + filepath.Base(chartPath)},
+		})
+	}
+	payload["embeds"] = embeds
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+	_ = writer.WriteField("payload_json", string(jsonData))
+
+	for i, chartPath := range charts {
+		if err := attachFile(writer, fmt.Sprintf("files[%d]", i), chartPath); err != nil {
+			return err
+		}
+	}
+	writer.Close()
+
+	resp, err := n.client.Post(n.webhookURL, writer.FormDataContentType(), body)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// attachFile copies a file into a multipart form field
+func attachFile(writer *multipart.Writer, field, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	defer file.Close()
+
+	part, err := writer.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("creating form file: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return fmt.Errorf("copying file: %w", err)
+	}
+	return nil
+}
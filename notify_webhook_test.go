@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSlackNotifier(t *testing.T) {
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/services/T000/B000/XXX" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// The webhook URL is the credential, so it must be posted to as is
+	n := NewSlackNotifier(server.URL + "/services/T000/B000/XXX")
+	if err := n.Notify(testReport()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	text := payload["text"]
+	for _, want := range []string{"*Financial Report 2024-01*", "⚠️ Groceries over budget", "Remaining: €512.30", "• Food &amp; Drinks: €123.45"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text %q doesn't contain %q", text, want)
+		}
+	}
+}
+
+func TestSlackNotifierError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewSlackNotifier(server.URL).Notify(testReport())
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "invalid_token") {
+		t.Fatalf("Notify error = %v, want the status and body", err)
+	}
+}
+
+func TestDiscordNotifier(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := NewDiscordNotifier(server.URL).Notify(testReport()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	content, _ := payload["content"].(string)
+	if !strings.Contains(content, "**Financial Report 2024-01**") || !strings.Contains(content, "• Food & Drinks: €123.45") {
+		t.Errorf("unexpected content %q", content)
+	}
+}
+
+func TestDiscordNotifierWithCharts(t *testing.T) {
+	chart := writeTestChart(t, "budget_chart.png")
+
+	var payload map[string]interface{}
+	var file []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parsing multipart form: %v", err)
+			return
+		}
+		json.Unmarshal([]byte(r.FormValue("payload_json")), &payload)
+		f, header, err := r.FormFile("files[0]")
+		if err != nil {
+			t.Errorf("missing files[0]: %v", err)
+			return
+		}
+		defer f.Close()
+		if header.Filename != "budget_chart.png" {
+			t.Errorf("filename = %q", header.Filename)
+		}
+		file, _ = io.ReadAll(f)
+	}))
+	defer server.Close()
+
+	if err := NewDiscordNotifier(server.URL).Notify(testReport(chart)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if string(file) != "\x89PNG test chart" {
+		t.Errorf("uploaded file = %q", file)
+	}
+	embeds, _ := payload["embeds"].([]interface{})
+	if len(embeds) != 1 {
+		t.Fatalf("embeds = %v, want one", payload["embeds"])
+	}
+	image := embeds[0].(map[string]interface{})["image"].(map[string]interface{})
+	if image["url"] != "attachment://budget_chart.png" {
+		t.Errorf("embed url = %v", image["url"])
+	}
+}
+
+func TestDiscordNotifierSplitsLongReports(t *testing.T) {
+	report := testReport(writeTestChart(t, "chart.png"))
+	for i := 0; i < 100; i++ {
+		report.Categories = append(report.Categories, ReportCategory{Name: fmt.Sprintf("Category number %d with a long name", i), Amount: float64(i)})
+	}
+
+	var contents []string
+	var charts []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			r.ParseMultipartForm(1 << 20)
+			json.Unmarshal([]byte(r.FormValue("payload_json")), &payload)
+			charts = append(charts, len(contents))
+		} else {
+			json.NewDecoder(r.Body).Decode(&payload)
+		}
+		content, _ := payload["content"].(string)
+		contents = append(contents, content)
+	}))
+	defer server.Close()
+
+	if err := NewDiscordNotifier(server.URL).Notify(report); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(contents) < 2 {
+		t.Fatalf("sent %d messages, want the report split", len(contents))
+	}
+	for i, content := range contents {
+		if n := utf8.RuneCountInString(content); n > maxDiscordContentLength {
+			t.Errorf("message %d has %d characters, Discord allows %d", i, n, maxDiscordContentLength)
+		}
+	}
+	if len(charts) != 1 || charts[0] != 0 {
+		t.Errorf("charts sent with messages %v, want only the first", charts)
+	}
+	if joined := strings.Join(contents, ""); joined != formatReportText(report, markdownStyle) {
+		t.Error("the messages together aren't the whole report")
+	}
+}
+
+func TestDiscordNotifierError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Unknown Webhook"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	chart := writeTestChart(t, "chart.png")
+	for _, report := range []Report{testReport(), testReport(chart)} {
+		err := NewDiscordNotifier(server.URL).Notify(report)
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("Notify with %d charts error = %v, want status 404", len(report.Charts), err)
+		}
+	}
+}
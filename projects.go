@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultProjectBudgetsFile = "project_budgets.json"
+
+	// projectsPerPage is the largest page Moneybird returns for projects
+	projectsPerPage = 100
+
+	// budgetBarWidth is the width of a full budget in the progress bars
+	budgetBarWidth = 30
+)
+
+// Project is a Moneybird project. Bookings and document lines are tagged with
+// one, e.g. a client project or a household project like "Verbouwing 2025".
+type Project struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	State  string   `json:"state"` // active or archived
+	Budget *float64 `json:"budget"`
+}
+
+// GetProjects fetches all projects, active and archived
+func (c *Client) GetProjects() ([]Project, error) {
+	var projects []Project
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("projects.json?filter=state:all&per_page=%d&page=%d", projectsPerPage, page)
+		body, err := c.doRequest("GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var batch []Project
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshaling response: %w", err)
+		}
+		projects = append(projects, batch...)
+		if len(batch) < projectsPerPage {
+			return projects, nil
+		}
+	}
+}
+
+// loadProjectBudgets reads the local project budgets, by project name or ID.
+// They replace the budget set in Moneybird. A missing file means no budgets.
+func loadProjectBudgets(filename string) (map[string]float64, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading project budgets: %w", err)
+	}
+
+	var budgets map[string]float64
+	if err := json.Unmarshal(data, &budgets); err != nil {
+		return nil, fmt.Errorf("unmarshaling project budgets: %w", err)
+	}
+	return budgets, nil
+}
+
+// ProjectReport is what was booked on a project in a period. Amounts have the
+// booking sign: spending is negative, revenue positive.
+type ProjectReport struct {
+	Project   Project
+	ByAccount map[string]float64 // ledger account ID → total
+	ByMonth   map[string]float64 // YYYY-MM → spend in that month
+	Revenue   float64
+	Spend     float64
+	Budget    float64 // 0 when the project has no budget
+}
+
+// Result is the project's revenue minus what was spent on it
+func (r *ProjectReport) Result() float64 {
+	return r.Revenue - r.Spend
+}
+
+// projectReports totals the bookings, document lines and sales invoice lines
+// tagged with a project, per project and ledger account. Lines count in
+// proportion to how much of the document or invoice was paid, like in
+// aggregateTotals.
+func projectReports(snap *Snapshot, projects []Project, budgets map[string]float64) []*ProjectReport {
+	accountMap := make(map[string]LedgerAccount)
+	for _, acc := range snap.LedgerAccounts {
+		accountMap[acc.ID] = acc
+	}
+
+	reports := make(map[string]*ProjectReport)
+	add := func(projectID *string, accountID, date string, amount float64) {
+		if projectID == nil || *projectID == "" || accountID == "" {
+			return
+		}
+		report, ok := reports[*projectID]
+		if !ok {
+			report = &ProjectReport{
+				Project:   Project{ID: *projectID, Name: *projectID},
+				ByAccount: make(map[string]float64),
+				ByMonth:   make(map[string]float64),
+			}
+			reports[*projectID] = report
+		}
+		report.ByAccount[accountID] += amount
+
+		switch accountMap[accountID].AccountType {
+		case "revenue":
+			report.Revenue += amount
+		case "expenses", "equity":
+			report.Spend -= amount
+			if len(date) >= 7 {
+				report.ByMonth[date[:7]] -= amount
+			}
+		}
+	}
+
+	for _, mut := range snap.Mutations {
+		for _, booking := range mut.LedgerAccountBookings {
+			var amount float64
+			fmt.Sscanf(booking.Price, "%f", &amount)
+			add(booking.ProjectID, booking.LedgerAccountID, mut.Date, amount)
+		}
+
+		for _, payment := range mut.Payments {
+			var doc Document
+			var ok bool
+			switch payment.InvoiceType {
+			case "Document":
+				doc, ok = snap.Documents[payment.InvoiceID]
+			case "SalesInvoice":
+				doc, ok = snap.SalesInvoices[payment.InvoiceID]
+			}
+			if !ok {
+				continue
+			}
+			for _, line := range documentLineAmounts(snap, payment, doc) {
+				add(line.Detail.ProjectID, line.Detail.LedgerAccountID, mut.Date, line.Total())
+			}
+		}
+	}
+
+	// Name the projects and include the ones with a budget but no bookings yet
+	for _, project := range projects {
+		report, ok := reports[project.ID]
+		if !ok {
+			if project.Budget == nil && budgets[project.ID] == 0 && budgets[project.Name] == 0 {
+				continue
+			}
+			report = &ProjectReport{ByAccount: make(map[string]float64), ByMonth: make(map[string]float64)}
+			reports[project.ID] = report
+		}
+		report.Project = project
+	}
+
+	var sorted []*ProjectReport
+	for _, report := range reports {
+		if report.Project.Budget != nil {
+			report.Budget = *report.Project.Budget
+		}
+		if budget, ok := budgets[report.Project.ID]; ok {
+			report.Budget = budget
+		} else if budget, ok := budgets[report.Project.Name]; ok {
+			report.Budget = budget
+		}
+		sorted = append(sorted, report)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Project.Name < sorted[j].Project.Name
+	})
+	return sorted
+}
+
+// budgetBar draws how much of a budget is used, e.g. ███████░░░░
+func budgetBar(used, budget float64) string {
+	filled := int(math.Round(math.Min(used/budget, 1) * budgetBarWidth))
+	filled = max(filled, 0)
+	return strings.Repeat("█", filled) + strings.Repeat("░", budgetBarWidth-filled)
+}
+
+// printProjectReport writes a project's totals per ledger account and, with a
+// budget, how the spend built up month by month. Budget progress comes from
+// overall, the project over all stored history, so it doesn't depend on the
+// period reported.
+func printProjectReport(report, overall *ProjectReport, accountMap map[string]LedgerAccount) {
+	state := ""
+	if report.Project.State != "" && report.Project.State != "active" {
+		state = " (" + report.Project.State + ")"
+	}
+	fmt.Printf("\n%s%s:\n", report.Project.Name, state)
+
+	var accountIDs []string
+	for id := range report.ByAccount {
+		accountIDs = append(accountIDs, id)
+	}
+	sort.Slice(accountIDs, func(i, j int) bool {
+		return accountMap[accountIDs[i]].Name < accountMap[accountIDs[j]].Name
+	})
+	for _, id := range accountIDs {
+		name := id
+		if acc, ok := accountMap[id]; ok {
+			name = acc.Name
+		}
+		fmt.Printf("   %-30s €%10.2f\n", name, report.ByAccount[id])
+	}
+	fmt.Printf("   Revenue: €%.2f, spend: €%.2f, result: €%.2f\n", report.Revenue, report.Spend, report.Result())
+
+	if overall == nil || overall.Budget <= 0 {
+		return
+	}
+	left := overall.Budget - overall.Spend
+	fmt.Printf("   Budget: €%.2f, %.0f%% used, €%.2f left\n", overall.Budget, overall.Spend/overall.Budget*100, left)
+	if left < 0 {
+		fmt.Printf("   ⚠️  Over budget by €%.2f\n", -left)
+	}
+
+	var months []string
+	for month := range overall.ByMonth {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+	var cumulative float64
+	for _, month := range months {
+		cumulative += overall.ByMonth[month]
+		fmt.Printf("   %s  €%10.2f  %s %3.0f%%\n", month, cumulative, budgetBar(cumulative, overall.Budget), cumulative/overall.Budget*100)
+	}
+}
+
+// parseBound parses a -from or -to flag, a date or a month. Months start on
+// their first day, or end on their last day when end is set.
+func parseBound(value string, end bool) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date.Format("2006-01-02"), nil
+	}
+	month, err := time.Parse("2006-01", value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q, expected YYYY-MM-DD or YYYY-MM", value)
+	}
+	if end {
+		month = month.AddDate(0, 1, -1)
+	}
+	return month.Format("2006-01-02"), nil
+}
+
+// runProjects reports spend and revenue per project from the store, over its
+// whole history or the period given with -from and -to
+func runProjects(args []string) {
+	fs := flag.NewFlagSet("projects", flag.ExitOnError)
+	storeFile := fs.String("store", defaultStoreFile, "Local store of synced Moneybird data")
+	fromSnapshot := fs.String("from-snapshot", "", "Use a saved financial_data JSON snapshot instead of the store")
+	from := fs.String("from", "", "Start of the period (YYYY-MM-DD or YYYY-MM, default: all stored)")
+	to := fs.String("to", "", "End of the period (YYYY-MM-DD or YYYY-MM, default: all stored)")
+	name := fs.String("project", "", "Only report projects whose name contains this")
+	budgetsFile := fs.String("budgets", defaultProjectBudgetsFile, "File with project budgets by name or ID")
+	fs.Parse(args)
+
+	start, err := parseBound(*from, false)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	end, err := parseBound(*to, true)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	budgets, err := loadProjectBudgets(*budgetsFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Budgets are tracked over everything stored, the period only limits
+	// what is reported
+	var snap, all *Snapshot
+	var projects []Project
+	if *fromSnapshot != "" {
+		if all, err = loadSnapshot(*fromSnapshot); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		projects = all.Projects
+
+		period := *all
+		period.Mutations = nil
+		for _, mut := range all.Mutations {
+			if (start == "" || mut.Date >= start) && (end == "" || mut.Date <= end) {
+				period.Mutations = append(period.Mutations, mut)
+			}
+		}
+		snap = &period
+	} else {
+		store, err := OpenStore(*storeFile)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		snap = store.Snapshot(start, end)
+		all = store.Snapshot("", "")
+		projects = store.ProjectList()
+	}
+	overall := make(map[string]*ProjectReport)
+	for _, report := range projectReports(all, projects, budgets) {
+		overall[report.Project.ID] = report
+	}
+
+	accountMap := make(map[string]LedgerAccount)
+	for _, acc := range snap.LedgerAccounts {
+		accountMap[acc.ID] = acc
+	}
+
+	period := "all stored transactions"
+	if start != "" || end != "" {
+		period = fmt.Sprintf("%s to %s", start, end)
+	}
+	fmt.Printf("Projects, %s:\n", period)
+
+	var reported int
+	for _, report := range projectReports(snap, projects, budgets) {
+		if *name != "" && !strings.Contains(strings.ToLower(report.Project.Name), strings.ToLower(*name)) {
+			continue
+		}
+		printProjectReport(report, overall[report.Project.ID], accountMap)
+		reported++
+	}
+	if reported == 0 {
+		fmt.Println("   No bookings tagged with a project")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProjectReportsCountSalesInvoices(t *testing.T) {
+	project := "client"
+	snap := &Snapshot{
+		LedgerAccounts: []LedgerAccount{
+			{ID: "sales", AccountType: "revenue"},
+			{ID: "hosting", AccountType: "expenses"},
+		},
+		Documents: map[string]Document{
+			"doc": {
+				ID:                "doc",
+				TotalPriceInclTax: "100.00",
+				PricesAreInclTax:  true,
+				Details:           []DocumentDetail{{LedgerAccountID: "hosting", Price: "100.00", ProjectID: &project}},
+			},
+		},
+		SalesInvoices: map[string]Document{
+			"inv": {
+				ID:                "inv",
+				TotalPriceInclTax: "1000.00",
+				PricesAreInclTax:  true,
+				Details: []DocumentDetail{
+					{LedgerAccountID: "sales", Price: "800.00", ProjectID: &project},
+					{LedgerAccountID: "sales", Price: "200.00"}, // not on the project
+				},
+			},
+		},
+		Mutations: []FinancialMutation{
+			{
+				ID:       "paid",
+				Date:     "2026-03-02",
+				Amount:   "-100.00",
+				Payments: []Payment{{InvoiceType: "Document", InvoiceID: "doc", Price: "100.00"}},
+			},
+			{
+				// The client paid half of the invoice
+				ID:       "received",
+				Date:     "2026-03-20",
+				Amount:   "500.00",
+				Payments: []Payment{{InvoiceType: "SalesInvoice", InvoiceID: "inv", Price: "500.00"}},
+			},
+		},
+	}
+
+	reports := projectReports(snap, nil, nil)
+	if len(reports) != 1 {
+		t.Fatalf("got %d project reports, want 1", len(reports))
+	}
+	report := reports[0]
+	if math.Abs(report.Revenue-400) > 0.005 {
+		t.Errorf("revenue = %.2f, want 400.00 (half of the project's invoice line)", report.Revenue)
+	}
+	if math.Abs(report.Spend-100) > 0.005 {
+		t.Errorf("spend = %.2f, want 100.00", report.Spend)
+	}
+	if math.Abs(report.ByAccount["sales"]-400) > 0.005 {
+		t.Errorf("sales account = %.2f, want 400.00", report.ByAccount["sales"])
+	}
+	if math.Abs(report.Result()-300) > 0.005 {
+		t.Errorf("result = %.2f, want 300.00", report.Result())
+	}
+}
+
+func TestStoreSnapshotIncludesSalesInvoices(t *testing.T) {
+	store, err := OpenStore(t.TempDir() + "/store.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.PutFinancialMutation(FinancialMutation{
+		ID:       "received",
+		Date:     "2026-03-20",
+		Amount:   "500.00",
+		Payments: []Payment{{InvoiceType: "SalesInvoice", InvoiceID: "inv", Price: "500.00"}},
+	})
+	store.PutSalesInvoice(Document{ID: "inv", Version: 1})
+	store.PutSalesInvoice(Document{ID: "other", Version: 1})
+
+	snap := store.Snapshot("2026-03-01", "2026-03-31")
+	if _, ok := snap.SalesInvoices["inv"]; !ok || len(snap.SalesInvoices) != 1 {
+		t.Errorf("snapshot sales invoices = %v, want only the paid invoice", snap.SalesInvoices)
+	}
+}
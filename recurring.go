@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// recurringAmountTolerance is how much a charge may differ from the previous
+	// one and still count as the same recurring payment, so price increases
+	// don't start a new series
+	recurringAmountTolerance = 0.25
+
+	// recurringMinOccurrences is the number of charges needed before monthly and
+	// quarterly payments count as recurring; yearly ones need two
+	recurringMinOccurrences = 3
+)
+
+// recurringFrequency describes a billing interval
+type recurringFrequency struct {
+	Name    string
+	Months  int
+	MinDays int // allowed range of days between two charges
+	MaxDays int
+	Grace   int // days after the expected date before a charge counts as missed
+}
+
+var recurringFrequencies = []recurringFrequency{
+	{Name: "monthly", Months: 1, MinDays: 25, MaxDays: 35, Grace: 7},
+	{Name: "quarterly", Months: 3, MinDays: 80, MaxDays: 100, Grace: 14},
+	{Name: "yearly", Months: 12, MinDays: 350, MaxDays: 380, Grace: 30},
+}
+
+// RecurringPayment is a charge that repeats at a fixed interval
+type RecurringPayment struct {
+	Name         string
+	Frequency    recurringFrequency
+	Occurrences  int
+	LastDate     time.Time
+	LastAmount   float64
+	NextDate     time.Time
+	NextAmount   float64
+	PrevAmount   float64 // amount before the last price change, 0 when unchanged
+	MissedCount  int     // expected charges that didn't happen
+	MonthlyShare float64 // amount per month, e.g. a yearly charge divided by 12
+}
+
+// PriceIncreased reports whether the last charge is higher than the ones before
+func (r RecurringPayment) PriceIncreased() bool {
+	return r.PrevAmount > 0 && r.LastAmount > r.PrevAmount*1.01
+}
+
+// recurringKey groups a mutation by counterparty: IBAN when known, otherwise
+// the normalized name
+func recurringKey(mut FinancialMutation) string {
+	if iban := normalizeIBAN(mut.ContraAccountNumber); iban != "" {
+		return "iban:" + iban
+	}
+	if name := normalizeMerchantName(mut.ContraAccountName); name != "" {
+		return "name:" + name
+	}
+	return ""
+}
+
+// detectRecurring finds recurring charges in the mutation history. Charges are
+// grouped by counterparty, then chained into series of similar amounts; a
+// series is recurring when the days between its charges fit a frequency.
+// Expected dates and missed charges are relative to asOf.
+func detectRecurring(history []FinancialMutation, asOf time.Time) []RecurringPayment {
+	type charge struct {
+		date   time.Time
+		amount float64
+		name   string
+	}
+
+	byCounterparty := make(map[string][]charge)
+	for _, mut := range history {
+		var amount float64
+		fmt.Sscanf(mut.Amount, "%f", &amount)
+		date, err := time.Parse("2006-01-02", mut.Date)
+		key := recurringKey(mut)
+		if amount >= 0 || err != nil || key == "" {
+			continue
+		}
+		byCounterparty[key] = append(byCounterparty[key], charge{date: date, amount: -amount, name: mut.ContraAccountName})
+	}
+
+	var result []RecurringPayment
+	for _, charges := range byCounterparty {
+		sort.Slice(charges, func(i, j int) bool {
+			return charges[i].date.Before(charges[j].date)
+		})
+
+		// Chain each charge onto the series whose last amount is close enough
+		var series [][]charge
+		for _, c := range charges {
+			placed := false
+			for i, s := range series {
+				last := s[len(s)-1].amount
+				if math.Abs(c.amount-last) <= last*recurringAmountTolerance {
+					series[i] = append(s, c)
+					placed = true
+					break
+				}
+			}
+			if !placed {
+				series = append(series, []charge{c})
+			}
+		}
+
+		for _, s := range series {
+			freq, ok := seriesFrequency(s[0].date, s[len(s)-1].date, len(s))
+			if !ok {
+				continue
+			}
+			var intervalsOK int
+			for i := 1; i < len(s); i++ {
+				days := int(s[i].date.Sub(s[i-1].date).Hours() / 24)
+				if days >= freq.MinDays && days <= freq.MaxDays {
+					intervalsOK++
+				}
+			}
+			// Allow the odd skipped or doubled month
+			if intervalsOK*3 < (len(s)-1)*2 {
+				continue
+			}
+
+			last := s[len(s)-1]
+			r := RecurringPayment{
+				Name:        last.name,
+				Frequency:   freq,
+				Occurrences: len(s),
+				LastDate:    last.date,
+				LastAmount:  last.amount,
+				NextAmount:  last.amount,
+			}
+			for i := len(s) - 2; i >= 0; i-- {
+				if math.Abs(s[i].amount-last.amount) > 0.005 {
+					r.PrevAmount = s[i].amount
+					break
+				}
+			}
+
+			// Step past every expected date that went by without a charge.
+			// Each date is counted from the last charge, so a charge on the
+			// 31st stays at the end of the month instead of drifting.
+			for step := 1; ; step++ {
+				r.NextDate = addMonths(last.date, step*freq.Months)
+				if !asOf.After(r.NextDate.AddDate(0, 0, freq.Grace)) {
+					break
+				}
+				r.MissedCount++
+			}
+			r.MonthlyShare = r.LastAmount / float64(freq.Months)
+
+			result = append(result, r)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].NextDate.Equal(result[j].NextDate) {
+			return result[i].NextDate.Before(result[j].NextDate)
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// addMonths adds months to a date, clamping the day to the end of the target
+// month: January 31 plus one month is February 28 or 29, not March 3
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return time.Date(first.Year(), first.Month(), min(t.Day(), lastDay), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// seriesFrequency picks the frequency matching the average interval of a
+// series, if it has enough charges for that frequency
+func seriesFrequency(first, last time.Time, count int) (recurringFrequency, bool) {
+	if count < 2 {
+		return recurringFrequency{}, false
+	}
+	average := last.Sub(first).Hours() / 24 / float64(count-1)
+	for _, freq := range recurringFrequencies {
+		if average < float64(freq.MinDays) || average > float64(freq.MaxDays) {
+			continue
+		}
+		if freq.Months < 12 && count < recurringMinOccurrences {
+			return recurringFrequency{}, false
+		}
+		return freq, true
+	}
+	return recurringFrequency{}, false
+}
+
+// fixedMonthlyObligations totals the monthly share of the recurring payments
+// that are still active, i.e. at most one charge missed
+func fixedMonthlyObligations(recurring []RecurringPayment) float64 {
+	var total float64
+	for _, r := range recurring {
+		if r.MissedCount <= 1 {
+			total += r.MonthlyShare
+		}
+	}
+	return total
+}
+
+// upcomingCharges totals the active recurring charges expected after asOf up
+// to and including the end date
+func upcomingCharges(recurring []RecurringPayment, asOf, end time.Time) float64 {
+	var total float64
+	for _, r := range recurring {
+		if r.MissedCount <= 1 && r.NextDate.After(asOf) && !r.NextDate.After(end) {
+			total += r.NextAmount
+		}
+	}
+	return total
+}
+
+// printRecurring writes the recurring payments to the console
+func printRecurring(recurring []RecurringPayment) {
+	if len(recurring) == 0 {
+		fmt.Println("   No recurring payments found")
+		return
+	}
+	for _, r := range recurring {
+		fmt.Printf("   %-30s %-9s €%8.2f  next %s", r.Name, r.Frequency.Name, r.NextAmount, r.NextDate.Format("2006-01-02"))
+		if r.PriceIncreased() {
+			fmt.Printf("  📈 up from €%.2f", r.PrevAmount)
+		}
+		if r.MissedCount > 0 {
+			fmt.Printf("  ❓ %d missed since %s", r.MissedCount, r.LastDate.Format("2006-01-02"))
+		}
+		fmt.Println()
+	}
+	fmt.Printf("   Fixed monthly obligations: €%.2f\n", fixedMonthlyObligations(recurring))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func mustDate(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestAddMonthsClampsToMonthEnd(t *testing.T) {
+	tests := []struct {
+		from   string
+		months int
+		want   string
+	}{
+		{"2024-01-31", 1, "2024-02-29"},
+		{"2023-01-31", 1, "2023-02-28"},
+		{"2024-01-31", 2, "2024-03-31"},
+		{"2024-03-31", 1, "2024-04-30"},
+		{"2024-11-30", 3, "2025-02-28"},
+		{"2024-02-29", 12, "2025-02-28"},
+		{"2024-01-15", 1, "2024-02-15"},
+	}
+	for _, tt := range tests {
+		if got := addMonths(mustDate(tt.from), tt.months).Format("2006-01-02"); got != tt.want {
+			t.Errorf("addMonths(%s, %d) = %s, want %s", tt.from, tt.months, got, tt.want)
+		}
+	}
+}
+
+func TestDetectRecurringMonthEnd(t *testing.T) {
+	var history []FinancialMutation
+	for i, d := range []string{"2024-01-31", "2024-02-29", "2024-03-31"} {
+		history = append(history, FinancialMutation{
+			ID:                fmt.Sprint(i),
+			Date:              d,
+			Amount:            "-12.99",
+			ContraAccountName: "Streaming Co",
+		})
+	}
+
+	// April 30, May 31 and June 30 went by without a charge
+	recurring := detectRecurring(history, mustDate("2024-07-10"))
+	if len(recurring) != 1 {
+		t.Fatalf("found %d recurring payments, want 1", len(recurring))
+	}
+	r := recurring[0]
+	if got := r.NextDate.Format("2006-01-02"); got != "2024-07-31" {
+		t.Errorf("NextDate = %s, want 2024-07-31", got)
+	}
+	if r.MissedCount != 3 {
+		t.Errorf("MissedCount = %d, want 3", r.MissedCount)
+	}
+}
@@ -0,0 +1,881 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// chartColors are the colors of the categories in the charts
+var chartColors = []drawing.Color{
+	drawing.Color{R: 255, G: 99, B: 132, A: 255},  // Red
+	drawing.Color{R: 54, G: 162, B: 235, A: 255},  // Blue
+	drawing.Color{R: 255, G: 206, B: 86, A: 255},  // Yellow
+	drawing.Color{R: 75, G: 192, B: 192, A: 255},  // Teal
+	drawing.Color{R: 153, G: 102, B: 255, A: 255}, // Purple
+	drawing.Color{R: 255, G: 159, B: 64, A: 255},  // Orange
+	drawing.Color{R: 46, G: 204, B: 113, A: 255},  // Green
+}
+
+// reportOptions are the command-line flags of the report command
+type reportOptions struct {
+	manualRevenue float64
+	alertsFile    string
+	alwaysSummary bool
+	storeFile     string
+	fullSync      bool
+	fromSnapshot  string
+	workers       int
+	rulesFile     string
+	applyRules    bool
+	splitsFile    string
+	ratesFile     string
+	balancesFile  string
+}
+
+// registerReportFlags defines the report flags on a flag set
+func registerReportFlags(fs *flag.FlagSet) *reportOptions {
+	opts := &reportOptions{}
+	fs.Float64Var(&opts.manualRevenue, "revenue", 0, "Manual revenue override (e.g., -revenue=12850.20)")
+	fs.StringVar(&opts.alertsFile, "alerts", "alerts.json", "File with alert rules")
+	fs.BoolVar(&opts.alwaysSummary, "summary", false, "Send the full summary even when no alert fires")
+	fs.StringVar(&opts.storeFile, "store", defaultStoreFile, "Local store of synced Moneybird data")
+	fs.BoolVar(&opts.fullSync, "full-sync", false, "Refetch everything instead of syncing only what changed")
+	fs.StringVar(&opts.fromSnapshot, "from-snapshot", "", "Build the report from a saved financial_data JSON snapshot, without network access")
+	fs.IntVar(&opts.workers, "workers", defaultWorkers, "Maximum number of concurrent Moneybird requests")
+	fs.StringVar(&opts.rulesFile, "rules", defaultRulesFile, "File with categorization rules, previewed in the report")
+	fs.BoolVar(&opts.applyRules, "apply-rules", false, "Book the transactions matched by categorization rules in Moneybird")
+	fs.StringVar(&opts.splitsFile, "splits", defaultSplitsFile, "File with split rules and overrides, previewed in the report")
+	fs.StringVar(&opts.ratesFile, "exchange-rates", defaultExchangeRatesFile, "File with exchange rates to euros, for foreign amounts without a base amount")
+	fs.StringVar(&opts.balancesFile, "balances", defaultBalancesFile, "File with known balances of the financial accounts without bank statements, for the net worth")
+	return opts
+}
+
+// runReport syncs the current month (or loads a snapshot) and reports on it
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	opts := registerReportFlags(fs)
+	fs.Parse(args)
+
+	if opts.fromSnapshot != "" {
+		fmt.Printf("Loading snapshot %s...\n", opts.fromSnapshot)
+		snap, err := loadSnapshot(opts.fromSnapshot)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("   %s to %s: %d ledger accounts, %d transactions, %d documents\n",
+			snap.PeriodStart, snap.PeriodEnd, len(snap.LedgerAccounts), len(snap.Mutations), len(snap.Documents))
+
+		if opts.applyRules {
+			fmt.Println("   Warning: -apply-rules needs Moneybird access, rules are only previewed")
+		}
+		if err := addExchangeRates(snap, opts.ratesFile); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		generateReport(snap, opts, nil)
+		return
+	}
+
+	client := newClientFromEnv()
+	client.SetWorkers(opts.workers)
+
+	// Get current month's date range
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := now // Use today as the end date
+
+	// Get previous month's date range
+	// now := time.Now()
+	// firstOfThisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	// monthStart := firstOfThisMonth.AddDate(0, -1, 0)
+	// monthEnd := firstOfThisMonth.AddDate(0, 0, -1)
+
+	fmt.Printf("Fetching financial data for %s...\n\n", monthStart.Format("January 2006"))
+
+	store, err := OpenStore(opts.storeFile)
+	if err != nil {
+		fmt.Printf("Error opening store: %v\n", err)
+		os.Exit(1)
+	}
+
+	snap, err := fetchSnapshot(client, store, monthStart, monthEnd, opts.fullSync)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if opts.applyRules {
+		fmt.Println("\nApplying categorization rules...")
+		rules, err := loadCategorizationRules(opts.rulesFile, snap.LedgerAccounts)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		splits, err := loadSplits(opts.splitsFile, snap.LedgerAccounts)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Transactions that are going to be split are left to the split
+		// command, internal transfers aren't categorized at all
+		candidates := withoutTransfers(snap.Mutations, transferConfigFromEnv())
+		hits, conflicts := matchRules(rules, withSplitBookings(candidates, planSplits(splits, candidates)))
+		booked, err := applyRuleHits(client, store, hits)
+		printRuleResults(hits[:booked], conflicts, true)
+		if err != nil {
+			fmt.Printf("   Error: %v\n", err)
+		}
+		snap = store.Snapshot(snap.PeriodStart, snap.PeriodEnd)
+	}
+
+	// Months reported with a manual revenue keep it for the savings trend
+	if opts.manualRevenue > 0 {
+		store.Revenue[monthStart.Format("2006-01")] = opts.manualRevenue
+	}
+
+	// Record this month's net worth, and last month's again at its month end
+	// to compare with
+	if balances, err := loadKnownBalances(opts.balancesFile); err != nil {
+		fmt.Printf("   Warning: not recording net worth: %v\n", err)
+	} else {
+		recordMonthNetWorth(store, monthStart, balanceAnchors(store, balances))
+	}
+
+	if err := store.SaveSynced(); err != nil {
+		fmt.Printf("   Warning: could not save store: %v\n", err)
+	}
+
+	if err := addExchangeRates(snap, opts.ratesFile); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	generateReport(snap, opts, store)
+
+	// Save detailed data
+	filename := snapshotFilename(monthStart)
+	if err := snap.save(filename); err != nil {
+		fmt.Printf("\nWarning: Could not save detailed JSON: %v\n", err)
+	} else {
+		fmt.Printf("\nDetailed data saved to %s\n", filename)
+	}
+}
+
+// fetchSnapshot syncs the store with Moneybird for the period [start, end] and
+// returns the data needed for the report
+func fetchSnapshot(client *Client, store *Store, start, end time.Time, fullSync bool) (*Snapshot, error) {
+	periodStart := start.Format("2006-01-02")
+	periodEnd := end.Format("2006-01-02")
+
+	// Sync ledger accounts
+	fmt.Println("1. Syncing ledger accounts, tax rates, contacts, projects and financial accounts...")
+	var accountStats SyncStats
+	var accountsErr error
+	if !fullSync {
+		accountStats, accountsErr = syncLedgerAccounts(client, store)
+		if accountsErr != nil {
+			fmt.Printf("   Incremental sync failed, fetching all accounts: %v\n", accountsErr)
+		}
+	}
+	if fullSync || accountsErr != nil {
+		fetched, err := client.GetLedgerAccounts()
+		if err != nil {
+			return nil, fmt.Errorf("fetching accounts: %w", err)
+		}
+		accountStats = store.SyncLedgerAccounts(fetched)
+	}
+	fmt.Printf("   Found %d ledger accounts (%s)\n", len(store.LedgerAccounts), accountStats)
+
+	// Tax rates have no synchronization endpoint, but the list is short. The
+	// stored rates are used when fetching fails.
+	rates, err := client.GetTaxRates()
+	if err != nil {
+		fmt.Printf("   Fetching tax rates failed, using %d stored rates: %v\n", len(store.TaxRates), err)
+	} else {
+		rateStats := store.SyncTaxRates(rates)
+		fmt.Printf("   Found %d tax rates (%s)\n", len(store.TaxRates), rateStats)
+	}
+
+	// Contacts are only used to recognize merchants, so a failed sync isn't fatal
+	contactStats, err := syncContacts(client, store)
+	if err != nil {
+		fmt.Printf("   Syncing contacts failed, using %d stored contacts: %v\n", len(store.Contacts), err)
+	} else {
+		fmt.Printf("   Found %d contacts (%s)\n", len(store.Contacts), contactStats)
+	}
+
+	// Projects are only used by the projects report, so a failed fetch isn't fatal
+	projects, err := client.GetProjects()
+	if err != nil {
+		fmt.Printf("   Fetching projects failed, using %d stored projects: %v\n", len(store.Projects), err)
+	} else {
+		projectStats := store.SyncProjects(projects)
+		fmt.Printf("   Found %d projects (%s)\n", len(store.Projects), projectStats)
+	}
+
+	// Financial accounts are only used by the cash flow report
+	financialAccounts, err := client.GetFinancialAccounts()
+	if err != nil {
+		fmt.Printf("   Fetching financial accounts failed, using %d stored accounts: %v\n", len(store.FinancialAccounts), err)
+	} else {
+		financialStats := store.SyncFinancialAccounts(financialAccounts)
+		fmt.Printf("   Found %d financial accounts (%s)\n", len(store.FinancialAccounts), financialStats)
+	}
+
+	// Sync financial mutations
+	fmt.Printf("\n2. Syncing transactions...\n")
+	var mutationStats SyncStats
+	var mutationsErr error
+	if !fullSync {
+		mutationStats, mutationsErr = syncFinancialMutations(client, store, periodStart, periodEnd)
+		if mutationsErr != nil {
+			fmt.Printf("   Incremental sync failed, fetching all transactions: %v\n", mutationsErr)
+		}
+	}
+	if fullSync || mutationsErr != nil {
+		fetched, err := fetchMutationsInChunks(client, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("fetching transactions: %w", err)
+		}
+		mutationStats = store.SyncMutationsBetween(periodStart, periodEnd, fetched)
+	}
+	store.SyncedPeriods = store.SyncedPeriods.add(periodStart, periodEnd)
+	allMutations := store.MutationsBetween(periodStart, periodEnd)
+
+	fmt.Printf("   Total: %d transactions (%s)\n", len(allMutations), mutationStats)
+
+	// Balances are anchored on the latest bank statements; without them the
+	// stored balances or balances.json are used
+	if updated, err := syncStatementBalances(client, store); err != nil {
+		fmt.Printf("   Fetching statement balances failed, using %d stored balances: %v\n", len(store.Balances), err)
+	} else {
+		fmt.Printf("   Statement balances: %d of %d accounts, %d updated\n", len(store.Balances), len(store.FinancialAccounts), updated)
+	}
+
+	// Collect all unique document IDs
+	fmt.Println("\n3. Syncing documents...")
+	uniqueDocIDs := make(map[string]bool)
+	for _, mut := range allMutations {
+		for _, payment := range mut.Payments {
+			if payment.InvoiceType == "Document" {
+				uniqueDocIDs[payment.InvoiceID] = true
+			}
+		}
+	}
+
+	if len(uniqueDocIDs) > 0 {
+		var documentStats SyncStats
+		var documentsErr error
+		var missingDocs []string
+		if !fullSync {
+			documentStats, missingDocs, documentsErr = syncDocuments(client, store, uniqueDocIDs)
+			if documentsErr != nil {
+				fmt.Printf("   Incremental sync failed, fetching all documents: %v\n", documentsErr)
+			}
+		}
+		if fullSync || documentsErr != nil {
+			docIDs := make([]string, 0, len(uniqueDocIDs))
+			for id := range uniqueDocIDs {
+				docIDs = append(docIDs, id)
+			}
+			sort.Strings(docIDs)
+
+			fmt.Printf("   Fetching %d unique documents...\n", len(docIDs))
+
+			docs, missing, err := client.GetDocuments(docIDs)
+			if err != nil {
+				return nil, fmt.Errorf("fetching documents: %w", err)
+			}
+			fmt.Printf("   Found %s\n", documentTypeCounts(docs))
+			for _, doc := range docs {
+				documentStats.record(store.PutDocument(doc))
+			}
+			missingDocs = missing
+		}
+		fmt.Printf("   Documents: %s\n", documentStats)
+		if len(missingDocs) > 0 {
+			fmt.Printf("   ⚠️  %d referenced documents not found in Moneybird: %s\n",
+				len(missingDocs), strings.Join(missingDocs, ", "))
+		}
+	}
+
+	// Sales invoices are only used to book revenue on projects, so a failed
+	// sync isn't fatal
+	uniqueInvoiceIDs := make(map[string]bool)
+	for _, mut := range allMutations {
+		for _, payment := range mut.Payments {
+			if payment.InvoiceType == "SalesInvoice" {
+				uniqueInvoiceIDs[payment.InvoiceID] = true
+			}
+		}
+	}
+	if len(uniqueInvoiceIDs) > 0 {
+		invoiceStats, missingInvoices, err := syncSalesInvoices(client, store, uniqueInvoiceIDs)
+		if err != nil {
+			fmt.Printf("   Syncing sales invoices failed, using %d stored invoices: %v\n", len(store.SalesInvoices), err)
+		} else {
+			fmt.Printf("   Sales invoices: %s\n", invoiceStats)
+		}
+		if len(missingInvoices) > 0 {
+			fmt.Printf("   ⚠️  %d referenced sales invoices not found in Moneybird: %s\n",
+				len(missingInvoices), strings.Join(missingInvoices, ", "))
+		}
+	}
+
+	return store.Snapshot(periodStart, periodEnd), nil
+}
+
+// rootAccount walks up the parent chain to the top-level account
+func rootAccount(acc LedgerAccount, accountMap map[string]LedgerAccount) LedgerAccount {
+	for acc.ParentID != nil && *acc.ParentID != "" {
+		parent, exists := accountMap[*acc.ParentID]
+		if !exists {
+			break
+		}
+		acc = parent
+	}
+	return acc
+}
+
+// aggregateTotals sums the bookings and payments of the snapshot's mutations
+// per ledger account ID
+func aggregateTotals(snap *Snapshot, accounts []LedgerAccount) (totals map[string]float64, bookingsProcessed, paymentsProcessed int) {
+	totals = make(map[string]float64)
+
+	// Find the Omzet (revenue) account ID
+	var omzetAccountID string
+	for _, acc := range accounts {
+		if acc.Name == "Omzet" && acc.AccountType == "revenue" {
+			omzetAccountID = acc.ID
+			break
+		}
+	}
+
+	for _, mut := range snap.Mutations {
+		// Process ledger account bookings (direct categorizations)
+		for _, booking := range mut.LedgerAccountBookings {
+			var amount float64
+			fmt.Sscanf(booking.Price, "%f", &amount)
+			totals[booking.LedgerAccountID] += amount
+			bookingsProcessed++
+		}
+
+		// Process payments (linked to documents/invoices)
+		for _, payment := range mut.Payments {
+			amount := paymentBase(payment)
+
+			if payment.InvoiceType == "SalesInvoice" {
+				// Sales invoices are revenue
+				totals[omzetAccountID] += amount
+				paymentsProcessed++
+			} else if payment.InvoiceType == "Document" {
+				// Look up document details
+				if doc, ok := snap.Documents[payment.InvoiceID]; ok && len(doc.lines()) > 0 {
+					// Add each detail to its respective ledger account, in
+					// proportion to how much of the document this payment covers,
+					// converted to euros for foreign currency documents
+					for _, line := range documentLineAmounts(snap, payment, doc) {
+						if line.Detail.LedgerAccountID != "" {
+							totals[line.Detail.LedgerAccountID] += line.Total()
+						}
+					}
+					paymentsProcessed++
+				}
+				// Skip if document not found
+			} else if payment.LedgerAccountID != "" {
+				// Other payment types use their ledger account
+				totals[payment.LedgerAccountID] += amount
+				paymentsProcessed++
+			}
+		}
+	}
+
+	return totals, bookingsProcessed, paymentsProcessed
+}
+
+// generateReport aggregates the snapshot, prints the summary, renders the chart
+// and sends notifications. The store is nil for offline reports: alert state
+// is then left untouched and uncategorized transactions aren't posted.
+func generateReport(snap *Snapshot, opts *reportOptions, store *Store) {
+	offline := store == nil
+	monthStart := snap.periodStart()
+	accounts := snap.LedgerAccounts
+	allMutations := snap.Mutations
+
+	// Analyses that look back further than this month use the store's full
+	// history; offline only the snapshot's own transactions are available
+	history := snap.Mutations
+	if !offline {
+		history = store.MutationsBetween("", snap.PeriodEnd)
+	}
+
+	// Internal transfers and savings movements aren't spending
+	transferConfig := transferConfigFromEnv()
+	transfers := detectTransfers(allMutations, transferConfig)
+	spending := spendingSnapshot(snap, transferConfig)
+	spendingHistory := withoutTransfers(history, transferConfig)
+
+	// Create account lookup map
+	accountMap := make(map[string]LedgerAccount)
+	for _, acc := range accounts {
+		accountMap[acc.ID] = acc
+	}
+
+	// Aggregate by ledger account
+	fmt.Println("\n4. Aggregating transactions by category...")
+	if len(snap.Documents) > 0 {
+		fmt.Printf("   Using %d documents\n", len(snap.Documents))
+	}
+
+	// Splits and rules that would categorize the remaining uncategorized
+	// transactions are previewed: the totals include them, Moneybird isn't
+	// changed. A split takes precedence over a categorization rule. Internal
+	// transfers are never categorized.
+	previewed := spending.Mutations
+	splits, err := loadSplits(opts.splitsFile, accounts)
+	if err != nil {
+		fmt.Printf("   Error loading splits: %v\n", err)
+	} else if plans := planSplits(splits, previewed); len(plans) > 0 {
+		fmt.Printf("   Splits (preview, %d transactions):\n", len(plans))
+		printSplitPlans(plans, false)
+		previewed = withSplitBookings(previewed, plans)
+	}
+
+	rules, err := loadCategorizationRules(opts.rulesFile, accounts)
+	if err != nil {
+		fmt.Printf("   Error loading categorization rules: %v\n", err)
+	} else if len(rules) > 0 {
+		hits, conflicts := matchRules(rules, previewed)
+		fmt.Printf("   Categorization rules (preview, %d matched, %d conflicts):\n", len(hits), len(conflicts))
+		printRuleResults(hits, conflicts, false)
+		previewed = withRuleBookings(previewed, hits)
+	}
+
+	aggregated := *snap
+	aggregated.Mutations = previewed
+	if len(transfers) > 0 {
+		fmt.Printf("   Left out %d internal transfers\n", len(transfers))
+	}
+
+	totals, bookingsProcessed, paymentsProcessed := aggregateTotals(&aggregated, accounts)
+
+	fmt.Printf("   Processed %d bookings and %d payments\n", bookingsProcessed, paymentsProcessed)
+	fmt.Printf("   Aggregated into %d categories\n", len(totals))
+
+	// Group by account type
+	fmt.Println("\n=== Monthly Summary ===")
+	typeGroups := make(map[string]map[string]float64)
+	for ledgerID, total := range totals {
+		if acc, ok := accountMap[ledgerID]; ok {
+			if typeGroups[acc.AccountType] == nil {
+				typeGroups[acc.AccountType] = make(map[string]float64)
+			}
+			typeGroups[acc.AccountType][acc.Name] = total
+		}
+	}
+	snap.Totals = typeGroups
+
+	// For equity accounts, group by root categories
+	fmt.Println("\nFamily Expenses (by root category):")
+	var totalFamilyExpenses float64
+	rootTotals := make(map[string]float64)
+	if _, ok := typeGroups["equity"]; ok {
+		for ledgerID, total := range totals {
+			if acc, ok := accountMap[ledgerID]; ok && acc.AccountType == "equity" {
+				totalFamilyExpenses += total
+				rootTotals[rootAccount(acc, accountMap).Name] += total
+			}
+		}
+
+		// Print sorted by amount
+		for name, amount := range rootTotals {
+			fmt.Printf("   %s: €%.2f\n", name, amount)
+		}
+		fmt.Printf("   TOTAL: €%.2f\n", totalFamilyExpenses)
+	}
+
+	// Print detailed equity for reference
+	if equityAccounts, ok := typeGroups["equity"]; ok {
+		fmt.Println("\nFamily Expenses (detailed):")
+		var totalEquity float64
+		for name, amount := range equityAccounts {
+			fmt.Printf("   %s: €%.2f\n", name, amount)
+			totalEquity += amount
+		}
+		fmt.Printf("   TOTAL: €%.2f\n", totalEquity)
+	}
+
+	// Print revenue
+	var totalRevenue float64
+	if revenueAccounts, ok := typeGroups["revenue"]; ok {
+		fmt.Println("\nRevenue:")
+		for name, amount := range revenueAccounts {
+			fmt.Printf("   %s: €%.2f\n", name, amount)
+			totalRevenue += amount
+		}
+		fmt.Printf("   TOTAL: €%.2f\n", totalRevenue)
+	}
+
+	// Print business expenses
+	var totalBusinessExpenses float64
+	if expenseAccounts, ok := typeGroups["expenses"]; ok {
+		fmt.Println("\nBusiness Expenses:")
+		for name, amount := range expenseAccounts {
+			fmt.Printf("   %s: €%.2f\n", name, amount)
+			totalBusinessExpenses += amount
+		}
+		fmt.Printf("   TOTAL: €%.2f\n", totalBusinessExpenses)
+	}
+
+	// Where the money went, per merchant
+	fmt.Println("\nTop Merchants:")
+	printMerchants(topMerchants(merchantAnalytics(spending), defaultTopMerchants))
+
+	// Money moved between our own accounts
+	fmt.Println("\nInternal Transfers:")
+	printTransfers(transfers)
+
+	// Subscriptions, insurance and other charges that repeat
+	fmt.Println("\nRecurring Payments:")
+	recurring := detectRecurring(spendingHistory, snap.periodEnd())
+	printRecurring(recurring)
+
+	// Unusual activity compared to the history
+	fmt.Println("\nAnomalies:")
+	documents := snap.Documents
+	if !offline {
+		documents = store.Documents
+	}
+	anomalies := detectAnomalies(AnomalyInput{
+		PeriodStart: monthStart,
+		Mutations:   spending.Mutations,
+		History:     spendingHistory,
+		Documents:   documents,
+		TaxRates:    snap.TaxRates,
+		Accounts:    accounts,
+	})
+	printAnomalies(anomalies)
+
+	// What still has to be categorized, with what the booking history suggests
+	fmt.Println("\nUncategorized Transactions:")
+	printUncategorized(spending.Mutations, trainSuggester(history, accounts))
+
+	// Show what was bought, from the documents behind the payments
+	printDocumentDrillDown(snap, accountMap)
+
+	// Documents paid in another currency, with what they came to in euros
+	if currencies := foreignCurrencyTotals(snap); len(currencies) > 0 {
+		fmt.Println("\nForeign Currencies:")
+		printCurrencyTotals(currencies)
+	}
+
+	// Split the documents paid this month into net and VAT per tax rate
+	if vatTotals := aggregateVAT(snap); len(vatTotals) > 0 {
+		fmt.Println("\nVAT on documents (by tax rate):")
+		var totalNet, totalVAT float64
+		for _, total := range vatTotals {
+			fmt.Printf("   %-30s net €%10.2f  VAT €%9.2f\n", total.TaxRate.Name, total.Net, total.VAT)
+			totalNet += total.Net
+			totalVAT += total.VAT
+		}
+		fmt.Printf("   %-30s net €%10.2f  VAT €%9.2f\n", "TOTAL", totalNet, totalVAT)
+	}
+
+	// Calculate family budget
+	fmt.Println("\n=== Family Budget Calculation ===")
+
+	// Use manual revenue if provided, otherwise use calculated
+	if opts.manualRevenue > 0 {
+		totalRevenue = opts.manualRevenue
+		fmt.Printf("Using manual revenue: €%.2f\n", totalRevenue)
+	}
+
+	// Calculate budget from revenue
+	vatRate := salesVATRate(snap.TaxRates)
+	revenueExclVAT, incomeTax, familyBudget := familyBudgetFrom(totalRevenue, totalBusinessExpenses, vatRate)
+	vatAmount := totalRevenue - revenueExclVAT
+
+	fmt.Printf("Gross Revenue: €%.2f\n", totalRevenue)
+	fmt.Printf("VAT (%.0f%%): €%.2f\n", vatRate*100, -vatAmount)
+	fmt.Printf("Revenue excl. VAT: €%.2f\n", revenueExclVAT)
+	fmt.Printf("Income Tax (%.0f%%): €%.2f\n", incomeTaxRate*100, -incomeTax)
+	fmt.Printf("Business Expenses: €%.2f\n", totalBusinessExpenses)
+	fmt.Printf("\n💰 Available Family Budget: €%.2f\n", familyBudget)
+
+	remaining := familyBudget + totalFamilyExpenses // expenses are negative
+	percentageUsed := (totalFamilyExpenses / familyBudget) * 100
+
+	fmt.Printf("\n💸 Family Spending: €%.2f\n", totalFamilyExpenses)
+	fmt.Printf("📊 Budget Used: %.1f%%\n", -percentageUsed)
+	fmt.Printf("💵 Remaining: €%.2f\n", remaining)
+	saved := savedAmount(transfers)
+	if saved != 0 {
+		fmt.Printf("🏦 Saved this month: €%.2f\n", saved)
+	}
+
+	// Fixed charges still due this month will come out of what remains
+	monthLastDay := monthStart.AddDate(0, 1, -1)
+	upcomingFixed := upcomingCharges(recurring, snap.periodEnd(), monthLastDay)
+	if upcomingFixed > 0 {
+		fmt.Printf("📅 Fixed charges still due: €%.2f\n", upcomingFixed)
+		fmt.Printf("💵 Remaining after fixed charges: €%.2f\n", remaining-upcomingFixed)
+	}
+
+	var netWorth string
+	if !offline {
+		if entry, ok := store.NetWorth[monthStart.Format("2006-01")]; ok {
+			if netWorth = netWorthLine(store, entry); netWorth != "" {
+				fmt.Printf("🏛️ Net worth: %s\n", netWorth)
+			} else {
+				fmt.Printf("🏛️ Net worth: unknown, no balance for %s\n", strings.Join(entry.Unknown, ", "))
+			}
+		}
+	}
+
+	// Savings rate and runway, over this month and the months before it
+	fmt.Println("\nSavings:")
+	savings := savingsMetrics(MonthFigures{
+		Month:            monthStart.Format("2006-01"),
+		Revenue:          totalRevenue,
+		BusinessExpenses: totalBusinessExpenses,
+		FamilyBudget:     familyBudget,
+		FamilySpending:   totalFamilyExpenses,
+		Partial:          snap.PeriodEnd < monthLastDay.Format("2006-01-02"),
+	}, store, snap.ExchangeRates, func(mutations []FinancialMutation) []FinancialMutation {
+		return previewBookings(mutations, transferConfig, splits, rules)
+	})
+	printSavingsMetrics(savings, vatRate)
+
+	// Generate pie chart
+	fmt.Println("\n5. Generating charts...")
+
+	// Sort categories by amount (largest to smallest)
+	type categoryAmount struct {
+		name   string
+		amount float64
+	}
+	var sortedCategories []categoryAmount
+	for name, amount := range rootTotals {
+		sortedCategories = append(sortedCategories, categoryAmount{name, amount})
+	}
+	sort.Slice(sortedCategories, func(i, j int) bool {
+		return sortedCategories[i].amount < sortedCategories[j].amount // ascending (most negative first)
+	})
+
+	// Prepare data for pie chart
+	var pieValues []chart.Value
+	// Add sorted categories to pie chart
+	for i, cat := range sortedCategories {
+		pieValues = append(pieValues, chart.Value{
+			Label: cat.name,
+			Value: -cat.amount, // Make positive for chart
+			Style: chart.Style{
+				FillColor: chartColors[i%len(chartColors)],
+			},
+		})
+	}
+
+	// Add remaining budget or over-budget indicator
+	if remaining > 0 {
+		pieValues = append(pieValues, chart.Value{
+			Label: "Remaining Budget",
+			Value: remaining,
+			Style: chart.Style{
+				FillColor: drawing.Color{R: 200, G: 200, B: 200, A: 255}, // Gray
+			},
+		})
+	} else if remaining < 0 {
+		pieValues = append(pieValues, chart.Value{
+			Label: "Over Budget",
+			Value: -remaining, // Make positive for display
+			Style: chart.Style{
+				FillColor: drawing.Color{R: 220, G: 53, B: 69, A: 255}, // Red
+			},
+		})
+	}
+
+	pie := chart.PieChart{
+		Width:  800,
+		Height: 600,
+		Values: pieValues,
+	}
+
+	chartFilename := fmt.Sprintf("budget_chart_%s.png", monthStart.Format("2006-01"))
+	var charts []string
+	chartFile, err := os.Create(chartFilename)
+	if err != nil {
+		fmt.Printf("   Error creating chart file: %v\n", err)
+	} else {
+		defer chartFile.Close()
+		err = pie.Render(chart.PNG, chartFile)
+		if err != nil {
+			fmt.Printf("   Error rendering chart: %v\n", err)
+		} else {
+			fmt.Printf("   ✓ Pie chart saved to %s\n", chartFilename)
+			charts = append(charts, chartFilename)
+		}
+	}
+
+	savingsChartFilename := fmt.Sprintf("savings_rate_%s.png", monthStart.Format("2006-01"))
+	if err := renderSavingsChart(savings, savingsChartFilename); err != nil {
+		fmt.Printf("   No savings rate chart: %v\n", err)
+	} else {
+		fmt.Printf("   ✓ Savings rate chart saved to %s\n", savingsChartFilename)
+		charts = append(charts, savingsChartFilename)
+	}
+
+	// Evaluate alert rules
+	fmt.Println("\n6. Checking alerts...")
+	alertConfig, err := loadAlertConfig(opts.alertsFile)
+	if err != nil {
+		fmt.Printf("   Error loading alert rules: %v\n", err)
+		os.Exit(1)
+	}
+
+	alertHistory, err := loadAlertState(alertStateFile)
+	if err != nil {
+		fmt.Printf("   Error loading alert state: %v\n", err)
+		os.Exit(1)
+	}
+
+	firing := evaluateAlerts(alertConfig, AlertInput{
+		Period:         monthStart.Format("2006-01"),
+		FamilyBudget:   familyBudget,
+		FamilySpending: totalFamilyExpenses,
+		Remaining:      remaining,
+		RootTotals:     rootTotals,
+		LeafTotals:     typeGroups["equity"],
+		Mutations:      spending.Mutations,
+		Anomalies:      anomalies,
+	})
+
+	// Offline reports are a rebuild of a past run, so they show every alert
+	// that fires and don't affect which alerts count as new
+	newAlerts := firing
+	if !offline {
+		newAlerts = alertHistory.update(firing)
+	}
+	fmt.Printf("   %d alerts firing, %d new\n", len(firing), len(newAlerts))
+
+	var warnings, escalations []Alert
+	for _, alert := range newAlerts {
+		fmt.Printf("   ⚠️  %s\n", alert.Message)
+		if alert.Escalation {
+			escalations = append(escalations, alert)
+		} else {
+			warnings = append(warnings, alert)
+		}
+	}
+
+	// Send notifications
+	notifiers := notifiersFromEnv()
+	if len(notifiers) > 0 {
+		fmt.Println("\n7. Sending notifications...")
+
+		summary := []SummaryLine{
+			{"Available Budget", fmt.Sprintf("€%.2f", familyBudget)},
+			{"Family Spending", fmt.Sprintf("€%.2f", totalFamilyExpenses)},
+			{"Budget Used", fmt.Sprintf("%.1f%%", -percentageUsed)},
+			{"Remaining", fmt.Sprintf("€%.2f", remaining)},
+		}
+		if upcomingFixed > 0 {
+			summary = append(summary,
+				SummaryLine{"Fixed Charges Due", fmt.Sprintf("€%.2f", upcomingFixed)},
+				SummaryLine{"Remaining After Fixed", fmt.Sprintf("€%.2f", remaining-upcomingFixed)})
+		}
+		if saved != 0 {
+			summary = append(summary, SummaryLine{"Saved This Month", fmt.Sprintf("€%.2f", saved)})
+		}
+		if netWorth != "" {
+			summary = append(summary, SummaryLine{"Net Worth", netWorth})
+		}
+		summary = append(summary, SummaryLine{"Savings Rate", formatRate(savings.Rate, savings.HasRate)})
+		if len(savings.Months) > 1 {
+			summary = append(summary, SummaryLine{fmt.Sprintf("Savings Rate (%d months)", len(savings.Months)), formatRate(savings.TrailingRate, savings.HasTrailingRate)})
+		}
+		summary = append(summary, SummaryLine{"Average Monthly Burn", fmt.Sprintf("€%.2f", savings.AverageBurn)})
+		if savings.MonthsCovered > 0 {
+			summary = append(summary, SummaryLine{"Months Covered", fmt.Sprintf("%.1f", savings.MonthsCovered)})
+		}
+		if obligations := fixedMonthlyObligations(recurring); obligations > 0 {
+			summary = append(summary, SummaryLine{"Fixed Monthly Obligations", fmt.Sprintf("€%.2f", obligations)})
+		}
+
+		// Each report remembers the alerts it carries, so they only count as
+		// sent once the report reached a channel
+		var reports []Report
+		var reportAlerts [][]Alert
+		if len(escalations) > 0 {
+			reports = append(reports, Report{
+				Title:   fmt.Sprintf("🚨 Over Budget - %s", monthStart.Format("January 2006")),
+				Alerts:  alertMessages(escalations),
+				Summary: summary,
+			})
+			reportAlerts = append(reportAlerts, escalations)
+		}
+		if offline || opts.alwaysSummary || len(warnings) > 0 {
+			report := Report{
+				Title:   fmt.Sprintf("💰 Budget Overview - %s", monthStart.Format("January 2006")),
+				Alerts:  alertMessages(warnings),
+				Summary: summary,
+				Charts:  charts,
+			}
+			for _, cat := range sortedCategories {
+				report.Categories = append(report.Categories, ReportCategory{Name: cat.name, Amount: -cat.amount})
+			}
+			reports = append(reports, report)
+			reportAlerts = append(reportAlerts, warnings)
+		}
+
+		if len(reports) == 0 {
+			fmt.Println("   No new alerts, nothing to send")
+		}
+
+		for i, report := range reports {
+			delivered := false
+			for _, notifier := range notifiers {
+				if err := notifier.Notify(report); err != nil {
+					fmt.Printf("   Error sending to %s: %v\n", notifier.Name(), err)
+				} else {
+					fmt.Printf("   ✓ Sent %q to %s successfully!\n", report.Title, notifier.Name())
+					delivered = true
+				}
+			}
+			if delivered {
+				alertHistory.markSent(reportAlerts[i])
+			}
+		}
+
+		// Alerts that couldn't be delivered anywhere stay new and are retried
+		// on the next run
+		if !offline {
+			if err := alertHistory.save(alertStateFile); err != nil {
+				fmt.Printf("   Error saving alert state: %v\n", err)
+			}
+		}
+	} else {
+		fmt.Println("\n⚠️  No notification channels configured (e.g. TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID)")
+	}
+
+	// Offer uncategorized transactions for booking through Telegram
+	telegramToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+	telegramChatID := os.Getenv("TELEGRAM_CHAT_ID")
+
+	if !offline && telegramToken != "" && telegramChatID != "" {
+		fmt.Println("\n8. Posting uncategorized transactions...")
+		bot := NewTelegramBot(telegramToken, telegramChatID)
+		posted, err := postUncategorized(bot, accounts, spending.Mutations, store.MutationsBetween("", ""), categorizeStateFile)
+		if err != nil {
+			fmt.Printf("   Error posting uncategorized transactions: %v\n", err)
+		}
+		fmt.Printf("   Posted %d new uncategorized transactions\n", posted)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const defaultRulesFile = "categorization_rules.json"
+
+// RuleConditions select mutations. Every condition that is set has to match:
+//
+//	contra_name         the counterparty name contains this text (case insensitive)
+//	contra_iban         the counterparty IBAN equals this (spaces ignored)
+//	message             the description matches this regular expression
+//	min_amount          the absolute amount is at least this
+//	max_amount          the absolute amount is at most this
+//	code                the bank's transaction code equals this
+//	financial_account   the mutation is on this financial account ID
+type RuleConditions struct {
+	ContraName       string   `json:"contra_name,omitempty"`
+	ContraIBAN       string   `json:"contra_iban,omitempty"`
+	Message          string   `json:"message,omitempty"`
+	MinAmount        *float64 `json:"min_amount,omitempty"`
+	MaxAmount        *float64 `json:"max_amount,omitempty"`
+	Code             string   `json:"code,omitempty"`
+	FinancialAccount string   `json:"financial_account,omitempty"`
+}
+
+// CategorizationRule books matching uncategorized mutations on a ledger
+// account, the name or ID of an equity or expenses ledger account
+type CategorizationRule struct {
+	Name string `json:"name"`
+	RuleConditions
+	LedgerAccount string `json:"ledger_account"`
+}
+
+// compiledConditions are validated conditions with the regular expression compiled
+type compiledConditions struct {
+	RuleConditions
+	message *regexp.Regexp
+}
+
+// compiledRule is a validated rule with its ledger account resolved
+type compiledRule struct {
+	Name       string
+	conditions compiledConditions
+	account    LedgerAccount
+}
+
+// RuleHit is an uncategorized mutation a rule assigns a ledger account to
+type RuleHit struct {
+	Mutation FinancialMutation
+	Rule     string
+	Account  LedgerAccount
+	Reasons  []string // the conditions that matched, for the report
+}
+
+// RuleConflict is a mutation matched by rules that disagree on the ledger account
+type RuleConflict struct {
+	Mutation FinancialMutation
+	Rules    []string
+	Accounts []string
+}
+
+// loadCategorizationRules reads and validates the rules file. A missing file
+// means there are no rules.
+func loadCategorizationRules(filename string, accounts []LedgerAccount) ([]compiledRule, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading rules file: %w", err)
+	}
+
+	var file struct {
+		Rules []CategorizationRule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("unmarshaling rules file: %w", err)
+	}
+
+	var rules []compiledRule
+	for i, rule := range file.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		compiled := compiledRule{Name: rule.Name}
+
+		if compiled.conditions, err = compileConditions(rule.RuleConditions); err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, err)
+		}
+		if compiled.account, err = resolveBookingAccount(rule.LedgerAccount, accounts); err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, err)
+		}
+
+		rules = append(rules, compiled)
+	}
+
+	return rules, nil
+}
+
+// compileConditions validates conditions and compiles the message pattern
+func compileConditions(c RuleConditions) (compiledConditions, error) {
+	compiled := compiledConditions{RuleConditions: c}
+	if c.ContraName == "" && c.ContraIBAN == "" && c.Message == "" && c.MinAmount == nil &&
+		c.MaxAmount == nil && c.Code == "" && c.FinancialAccount == "" {
+		return compiled, fmt.Errorf("has no conditions and would match everything")
+	}
+	if c.Message != "" {
+		var err error
+		if compiled.message, err = regexp.Compile("(?i)" + c.Message); err != nil {
+			return compiled, fmt.Errorf("invalid message pattern: %w", err)
+		}
+	}
+	return compiled, nil
+}
+
+// resolveBookingAccount finds an equity or expenses ledger account by ID or name
+func resolveBookingAccount(nameOrID string, accounts []LedgerAccount) (LedgerAccount, error) {
+	for _, acc := range accounts {
+		if acc.ID == nameOrID || strings.EqualFold(acc.Name, nameOrID) {
+			if acc.AccountType != "equity" && acc.AccountType != "expenses" {
+				return LedgerAccount{}, fmt.Errorf("ledger account %q is %s, not equity or expenses", acc.Name, acc.AccountType)
+			}
+			return acc, nil
+		}
+	}
+	return LedgerAccount{}, fmt.Errorf("unknown ledger account %q", nameOrID)
+}
+
+// match reports whether the conditions match a mutation and which ones did
+func (r compiledConditions) match(mut FinancialMutation) ([]string, bool) {
+	var reasons []string
+
+	if r.ContraName != "" {
+		if !strings.Contains(strings.ToLower(mut.ContraAccountName), strings.ToLower(r.ContraName)) {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("name contains %q", r.ContraName))
+	}
+	if r.ContraIBAN != "" {
+		if normalizeIBAN(mut.ContraAccountNumber) != normalizeIBAN(r.ContraIBAN) {
+			return nil, false
+		}
+		reasons = append(reasons, "IBAN "+normalizeIBAN(r.ContraIBAN))
+	}
+	if r.message != nil {
+		if !r.message.MatchString(mut.Message) {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("message matches /%s/", r.Message))
+	}
+	if r.MinAmount != nil || r.MaxAmount != nil {
+		var amount float64
+		fmt.Sscanf(mut.Amount, "%f", &amount)
+		amount = math.Abs(amount)
+		if (r.MinAmount != nil && amount < *r.MinAmount) || (r.MaxAmount != nil && amount > *r.MaxAmount) {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("amount €%.2f in range", amount))
+	}
+	if r.Code != "" {
+		if mut.Code != r.Code {
+			return nil, false
+		}
+		reasons = append(reasons, "code "+r.Code)
+	}
+	if r.FinancialAccount != "" {
+		if mut.FinancialAccountID != r.FinancialAccount {
+			return nil, false
+		}
+		reasons = append(reasons, "financial account "+r.FinancialAccount)
+	}
+
+	return reasons, true
+}
+
+// matchRules runs the rules over the uncategorized mutations. A mutation
+// matched by rules that book on different ledger accounts is a conflict and
+// gets no hit; otherwise the first matching rule wins.
+func matchRules(rules []compiledRule, mutations []FinancialMutation) ([]RuleHit, []RuleConflict) {
+	var hits []RuleHit
+	var conflicts []RuleConflict
+
+	for _, mut := range mutations {
+		if !isUncategorized(mut) {
+			continue
+		}
+
+		var matched []RuleHit
+		accounts := make(map[string]bool)
+		for _, rule := range rules {
+			if reasons, ok := rule.conditions.match(mut); ok {
+				matched = append(matched, RuleHit{Mutation: mut, Rule: rule.Name, Account: rule.account, Reasons: reasons})
+				accounts[rule.account.ID] = true
+			}
+		}
+
+		switch {
+		case len(matched) == 0:
+		case len(accounts) > 1:
+			conflict := RuleConflict{Mutation: mut}
+			for _, hit := range matched {
+				conflict.Rules = append(conflict.Rules, hit.Rule)
+				conflict.Accounts = append(conflict.Accounts, hit.Account.Name)
+			}
+			conflicts = append(conflicts, conflict)
+		default:
+			hits = append(hits, matched[0])
+		}
+	}
+
+	return hits, conflicts
+}
+
+// previewBookings returns the mutations without internal transfers, as if the
+// splits and then the categorization rules had been applied, like the report
+// previews them. A split takes precedence over a rule; either may be nil.
+func previewBookings(mutations []FinancialMutation, config TransferConfig, splits *splitConfig, rules []compiledRule) []FinancialMutation {
+	previewed := withoutTransfers(mutations, config)
+	if splits != nil {
+		previewed = withSplitBookings(previewed, planSplits(splits, previewed))
+	}
+	if len(rules) > 0 {
+		hits, _ := matchRules(rules, previewed)
+		previewed = withRuleBookings(previewed, hits)
+	}
+	return previewed
+}
+
+// withRuleBookings returns a copy of the mutations where every hit has a
+// booking on its rule's ledger account, as if the rules had been applied
+func withRuleBookings(mutations []FinancialMutation, hits []RuleHit) []FinancialMutation {
+	byID := make(map[string]RuleHit)
+	for _, hit := range hits {
+		byID[hit.Mutation.ID] = hit
+	}
+
+	previewed := make([]FinancialMutation, len(mutations))
+	for i, mut := range mutations {
+		previewed[i] = mut
+		if hit, ok := byID[mut.ID]; ok {
+			previewed[i].LedgerAccountBookings = []LedgerAccountBooking{{
+				FinancialMutationID: mut.ID,
+				LedgerAccountID:     hit.Account.ID,
+				Description:         "rule: " + hit.Rule,
+				Price:               mut.Amount,
+			}}
+		}
+	}
+	return previewed
+}
+
+// applyRuleHits books every hit in Moneybird and updates the store with the
+// booked mutations. It returns the number of bookings made.
+func applyRuleHits(client *Client, store *Store, hits []RuleHit) (int, error) {
+	var booked int
+	for _, hit := range hits {
+		mut, err := client.BookOnLedgerAccount(hit.Mutation.ID, hit.Account.ID, hit.Mutation.Amount, "")
+		if err != nil {
+			return booked, fmt.Errorf("booking %s on %s: %w", hit.Mutation.ID, hit.Account.Name, err)
+		}
+		store.PutFinancialMutation(*mut)
+		booked++
+	}
+	return booked, nil
+}
+
+// printRuleResults explains the rule hits and conflicts in the report
+func printRuleResults(hits []RuleHit, conflicts []RuleConflict, applied bool) {
+	verb := "would book"
+	if applied {
+		verb = "booked"
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Rule < hits[j].Rule
+	})
+	for _, hit := range hits {
+		fmt.Printf("   %s %s %s → %s (%s)\n", hit.Rule, verb, describeMutation(hit.Mutation), hit.Account.Name, strings.Join(hit.Reasons, ", "))
+	}
+	for _, conflict := range conflicts {
+		fmt.Printf("   ⚠️  Conflict for %s: %s disagree (%s), left uncategorized\n",
+			describeMutation(conflict.Mutation), strings.Join(conflict.Rules, ", "), strings.Join(conflict.Accounts, " vs "))
+	}
+	if len(hits) == 0 && len(conflicts) == 0 {
+		fmt.Println("   No uncategorized transactions matched a rule")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testRule compiles a rule that books on account when the contra name contains name
+func testRule(t *testing.T, ruleName, name string, account LedgerAccount) compiledRule {
+	t.Helper()
+	conditions, err := compileConditions(RuleConditions{ContraName: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compiledRule{Name: ruleName, conditions: conditions, account: account}
+}
+
+func TestPreviewBookingsLeavesTransfersAlone(t *testing.T) {
+	household := LedgerAccount{ID: "h", Name: "Huishouden", AccountType: "equity"}
+	rules := []compiledRule{testRule(t, "own name", "de vries", household)}
+	config := TransferConfig{
+		OwnIBANs:     map[string]bool{"NL02ABNA0123456789": true},
+		SavingsIBANs: map[string]bool{"NL02ABNA0123456789": true},
+	}
+	mutations := []FinancialMutation{
+		{ID: "save", Date: "2025-03-01", Amount: "-500.00", State: "unprocessed",
+			ContraAccountName: "J. de Vries", ContraAccountNumber: "NL02 ABNA 0123 4567 89"},
+		{ID: "gift", Date: "2025-03-02", Amount: "-25.00", State: "unprocessed",
+			ContraAccountName: "A. de Vries", ContraAccountNumber: "NL91ABNA0417164300"},
+	}
+
+	previewed := previewBookings(mutations, config, nil, rules)
+	if len(previewed) != 1 || previewed[0].ID != "gift" {
+		t.Fatalf("previewed = %+v, want only the gift", previewed)
+	}
+	if len(previewed[0].LedgerAccountBookings) != 1 || previewed[0].LedgerAccountBookings[0].LedgerAccountID != "h" {
+		t.Errorf("gift bookings = %+v, want the rule's booking", previewed[0].LedgerAccountBookings)
+	}
+}
+
+func TestMatchRulesConflicts(t *testing.T) {
+	household := LedgerAccount{ID: "h", Name: "Huishouden", AccountType: "equity"}
+	car := LedgerAccount{ID: "c", Name: "Auto", AccountType: "equity"}
+	rules := []compiledRule{
+		testRule(t, "supermarket", "jumbo", household),
+		testRule(t, "groceries", "jumbo", household),
+		testRule(t, "fuel", "shell", car),
+		testRule(t, "shell shop", "shell", household),
+	}
+	mutations := []FinancialMutation{
+		{ID: "jumbo", Date: "2025-03-01", Amount: "-40.00", State: "unprocessed", ContraAccountName: "Jumbo Utrecht"},
+		{ID: "shell", Date: "2025-03-02", Amount: "-60.00", State: "unprocessed", ContraAccountName: "Shell Station"},
+		{ID: "booked", Date: "2025-03-03", Amount: "-10.00", State: "unprocessed", ContraAccountName: "Jumbo",
+			LedgerAccountBookings: []LedgerAccountBooking{{LedgerAccountID: "h", Price: "-10.00"}}},
+	}
+
+	hits, conflicts := matchRules(rules, mutations)
+
+	// Rules agreeing on the account aren't a conflict, the first one wins
+	if len(hits) != 1 || hits[0].Mutation.ID != "jumbo" || hits[0].Rule != "supermarket" {
+		t.Errorf("hits = %+v, want the Jumbo charge by the first rule", hits)
+	}
+	if len(conflicts) != 1 || conflicts[0].Mutation.ID != "shell" {
+		t.Fatalf("conflicts = %+v, want the Shell charge", conflicts)
+	}
+	if !reflect.DeepEqual(conflicts[0].Rules, []string{"fuel", "shell shop"}) || !reflect.DeepEqual(conflicts[0].Accounts, []string{"Auto", "Huishouden"}) {
+		t.Errorf("conflict = %+v, want both rules and their accounts", conflicts[0])
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+const (
+	// incomeTaxRate is the share of the revenue excluding VAT set aside for tax
+	incomeTaxRate = 0.30
+
+	// trailingMonths is the window of the trailing savings rate and burn
+	trailingMonths = 12
+
+	// savingsBarWidth is the width of a 100% savings rate in the trend
+	savingsBarWidth = 20
+)
+
+// familyBudgetFrom works out what is left for the family from the revenue
+// including VAT and the (negative) business expenses
+func familyBudgetFrom(revenue, businessExpenses, vatRate float64) (revenueExclVAT, incomeTax, budget float64) {
+	revenueExclVAT = revenue / (1 + vatRate)
+	incomeTax = revenueExclVAT * incomeTaxRate
+	return revenueExclVAT, incomeTax, revenueExclVAT - incomeTax + businessExpenses
+}
+
+// MonthFigures are the budget figures of a month in the report's signs:
+// business expenses and family spending are negative
+type MonthFigures struct {
+	Month            string // YYYY-MM
+	Revenue          float64
+	BusinessExpenses float64
+	FamilyBudget     float64
+	FamilySpending   float64
+	Partial          bool // the month isn't over yet
+}
+
+// BusinessProfit is the revenue excluding VAT minus the business expenses
+func (f MonthFigures) BusinessProfit(vatRate float64) float64 {
+	revenueExclVAT, _, _ := familyBudgetFrom(f.Revenue, f.BusinessExpenses, vatRate)
+	return revenueExclVAT + f.BusinessExpenses
+}
+
+// Saved is the part of the family budget that wasn't spent
+func (f MonthFigures) Saved() float64 {
+	return f.FamilyBudget + f.FamilySpending
+}
+
+// SavingsRate is the share of the family budget that was saved, and false
+// when there was no budget to save from
+func (f MonthFigures) SavingsRate() (float64, bool) {
+	if f.FamilyBudget <= 0 {
+		return 0, false
+	}
+	return f.Saved() / f.FamilyBudget, true
+}
+
+// monthFigures aggregates a month from the store the way the report does:
+// with the bookings preview applied, without internal transfers and with the
+// manual revenue the month was reported with
+func monthFigures(store *Store, month time.Time, exchangeRates map[string]float64, preview func([]FinancialMutation) []FinancialMutation) (MonthFigures, bool) {
+	column, ok := monthColumn(store, month, exchangeRates, preview)
+	return column.Figures, ok
+}
+
+// monthColumn summarizes a month from the store like monthFigures, keeping
+// the per-category totals for the yearly overview
+func monthColumn(store *Store, month time.Time, exchangeRates map[string]float64, preview func([]FinancialMutation) []FinancialMutation) (MonthColumn, bool) {
+	snap := monthSnapshot(store, month)
+	if len(snap.Mutations) == 0 {
+		return MonthColumn{}, false
+	}
+	snap.ExchangeRates = exchangeRates
+	if preview != nil {
+		snap.Mutations = preview(snap.Mutations)
+	}
+	column := summarizeMonth(snap, month.Format("2006-01"))
+	if revenue, ok := store.Revenue[column.Figures.Month]; ok {
+		column.Figures.Revenue = revenue
+		_, _, column.Figures.FamilyBudget = familyBudgetFrom(revenue, column.Figures.BusinessExpenses, column.VATRate)
+	}
+	return column, true
+}
+
+// SavingsMetrics are the savings rate and runway derived from the monthly figures
+type SavingsMetrics struct {
+	Months          []MonthFigures // oldest first, the report's month last
+	Rate            float64
+	HasRate         bool
+	TrailingRate    float64
+	HasTrailingRate bool
+	AverageBurn     float64 // average family spending per month, positive
+	LiquidSavings   float64
+	MonthsCovered   float64 // 0 when the liquid savings are unknown
+}
+
+// savingsMetrics works out the metrics for the report's month from it and the
+// months before it in the store, previewed like the report's month. Without a
+// store only the month itself counts.
+func savingsMetrics(current MonthFigures, store *Store, exchangeRates map[string]float64, preview func([]FinancialMutation) []FinancialMutation) SavingsMetrics {
+	var metrics SavingsMetrics
+	if store != nil {
+		month, _ := time.Parse("2006-01", current.Month)
+		for i := trailingMonths - 1; i >= 1; i-- {
+			if figures, ok := monthFigures(store, month.AddDate(0, -i, 0), exchangeRates, preview); ok {
+				metrics.Months = append(metrics.Months, figures)
+			}
+		}
+	}
+	metrics.Months = append(metrics.Months, current)
+	metrics.Rate, metrics.HasRate = current.SavingsRate()
+
+	var saved, budget, burn float64
+	var burnMonths int
+	for _, figures := range metrics.Months {
+		saved += figures.Saved()
+		budget += figures.FamilyBudget
+		// A month that isn't over would pull the average burn down
+		if !figures.Partial || len(metrics.Months) == 1 {
+			burn -= figures.FamilySpending
+			burnMonths++
+		}
+	}
+	if budget > 0 {
+		metrics.TrailingRate, metrics.HasTrailingRate = saved/budget, true
+	}
+	if burnMonths > 0 {
+		metrics.AverageBurn = burn / float64(burnMonths)
+	}
+
+	if store != nil {
+		if liquid, ok := liquidSavings(store, current.Month, transferConfigFromEnv()); ok {
+			metrics.LiquidSavings = liquid
+			if metrics.AverageBurn > 0 && metrics.LiquidSavings > 0 {
+				metrics.MonthsCovered = metrics.LiquidSavings / metrics.AverageBurn
+			}
+		}
+	}
+	return metrics
+}
+
+// liquidSavings totals the month-end balances of the savings accounts, the
+// financial accounts in SAVINGS_IBANS, as recorded for the net worth. It is
+// false without savings accounts or when one's balance isn't known.
+func liquidSavings(store *Store, month string, config TransferConfig) (float64, bool) {
+	entry, ok := store.NetWorth[month]
+	if !ok {
+		return 0, false
+	}
+	unknown := make(map[string]bool)
+	for _, name := range entry.Unknown {
+		unknown[name] = true
+	}
+
+	var total float64
+	var found bool
+	for _, account := range store.FinancialAccountList() {
+		if !config.SavingsIBANs[normalizeIBAN(account.Identifier)] || account.Identifier == "" {
+			continue
+		}
+		if unknown[account.Name] {
+			return 0, false
+		}
+		total += entry.Accounts[account.Name]
+		found = true
+	}
+	return total, found
+}
+
+// formatRate formats a savings rate, or "n/a" without a budget
+func formatRate(rate float64, ok bool) string {
+	if !ok {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", rate*100)
+}
+
+// printSavingsMetrics writes the metrics and the savings rate per month
+func printSavingsMetrics(metrics SavingsMetrics, vatRate float64) {
+	current := metrics.Months[len(metrics.Months)-1]
+	fmt.Printf("   Savings rate this month: %s\n", formatRate(metrics.Rate, metrics.HasRate))
+	if len(metrics.Months) > 1 {
+		fmt.Printf("   Savings rate over %d months: %s\n", len(metrics.Months), formatRate(metrics.TrailingRate, metrics.HasTrailingRate))
+	}
+	fmt.Printf("   Business profit this month: €%.2f\n", current.BusinessProfit(vatRate))
+	fmt.Printf("   Average monthly burn: €%.2f\n", metrics.AverageBurn)
+	if metrics.MonthsCovered > 0 {
+		fmt.Printf("   Liquid savings of €%.2f cover %.1f months of expenses\n", metrics.LiquidSavings, metrics.MonthsCovered)
+	}
+
+	if len(metrics.Months) < 2 {
+		return
+	}
+	fmt.Println("   Trend:")
+	for _, figures := range metrics.Months {
+		rate, ok := figures.SavingsRate()
+		bar := ""
+		if ok {
+			filled := int(math.Round(math.Max(0, math.Min(rate, 1)) * savingsBarWidth))
+			bar = strings.Repeat("█", filled) + strings.Repeat("░", savingsBarWidth-filled)
+		}
+		fmt.Printf("   %s  saved €%9.2f of €%9.2f  %7s  %s\n", figures.Month, figures.Saved(), figures.FamilyBudget, formatRate(rate, ok), bar)
+	}
+}
+
+// renderSavingsChart draws the monthly and trailing savings rate. Months
+// without a family budget are left out.
+func renderSavingsChart(metrics SavingsMetrics, filename string) error {
+	monthly := chart.TimeSeries{
+		Name:  "Savings rate",
+		Style: chart.Style{StrokeColor: drawing.Color{R: 54, G: 162, B: 235, A: 255}, StrokeWidth: 2},
+	}
+	trailing := chart.TimeSeries{
+		Name:  "Trailing",
+		Style: chart.Style{StrokeColor: drawing.Color{R: 46, G: 204, B: 113, A: 255}, StrokeWidth: 2},
+	}
+	var ticks []chart.Tick
+	var saved, budget float64
+	for _, figures := range metrics.Months {
+		saved += figures.Saved()
+		budget += figures.FamilyBudget
+		rate, ok := figures.SavingsRate()
+		if !ok || budget <= 0 {
+			continue
+		}
+		month, _ := time.Parse("2006-01", figures.Month)
+		monthly.XValues = append(monthly.XValues, month)
+		monthly.YValues = append(monthly.YValues, rate*100)
+		trailing.XValues = append(trailing.XValues, month)
+		trailing.YValues = append(trailing.YValues, saved/budget*100)
+		ticks = append(ticks, chart.Tick{Value: chart.TimeToFloat64(month), Label: figures.Month})
+	}
+	if len(ticks) < 2 {
+		return fmt.Errorf("need at least two months with a budget to chart, have %d", len(ticks))
+	}
+
+	graph := chart.Chart{
+		Width:  900,
+		Height: 400,
+		XAxis:  chart.XAxis{Ticks: ticks},
+		YAxis: chart.YAxis{ValueFormatter: func(v interface{}) string {
+			return fmt.Sprintf("%.0f%%", v.(float64))
+		}},
+		Series: []chart.Series{monthly, trailing},
+	}
+	graph.Elements = []chart.Renderable{chart.Legend(&graph)}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("creating chart file: %w", err)
+	}
+	defer file.Close()
+	if err := graph.Render(chart.PNG, file); err != nil {
+		return fmt.Errorf("rendering chart: %w", err)
+	}
+	return nil
+}
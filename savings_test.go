@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMonthFiguresLikeTheReport(t *testing.T) {
+	store, err := OpenStore(t.TempDir() + "/store.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.LedgerAccounts["omzet"] = LedgerAccount{ID: "omzet", Name: "Omzet", AccountType: "revenue"}
+	store.LedgerAccounts["food"] = LedgerAccount{ID: "food", Name: "Boodschappen", AccountType: "equity"}
+	store.TaxRates["high"] = TaxRate{ID: "high", Percentage: "21", TaxRateType: "sales_invoice", Active: true}
+	store.FinancialMutations["m1"] = FinancialMutation{ID: "m1", Date: "2025-03-05", Amount: "-80.00", State: "unprocessed"}
+	store.FinancialMutations["m2"] = FinancialMutation{ID: "m2", Date: "2025-03-06", Amount: "-20.00",
+		LedgerAccountBookings: []LedgerAccountBooking{{LedgerAccountID: "food", Price: "-20.00"}}}
+	march := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	figures, ok := monthFigures(store, march, nil, nil)
+	if !ok || figures.FamilySpending != -20 {
+		t.Fatalf("figures = %+v, want only the booked spending", figures)
+	}
+
+	// The uncategorized charge counts once previewed, and the month keeps the
+	// revenue it was reported with
+	preview := func(mutations []FinancialMutation) []FinancialMutation {
+		return withRuleBookings(mutations, []RuleHit{{Mutation: mutations[0], Rule: "shop", Account: store.LedgerAccounts["food"]}})
+	}
+	store.Revenue["2025-03"] = 1210
+	figures, _ = monthFigures(store, march, nil, preview)
+	if figures.FamilySpending != -100 || figures.Revenue != 1210 {
+		t.Errorf("figures = %+v, want €100.00 spent and €1210.00 revenue", figures)
+	}
+	if math.Abs(figures.FamilyBudget-700) > 0.005 {
+		t.Errorf("family budget = %.2f, want 700.00 from the manual revenue", figures.FamilyBudget)
+	}
+}
+
+func TestLiquidSavingsOnlyCountsSavingsAccounts(t *testing.T) {
+	store := &Store{
+		FinancialAccounts: map[string]FinancialAccount{
+			"bank": {ID: "bank", Name: "Betaalrekening", Identifier: "NL91ABNA0417164300"},
+			"save": {ID: "save", Name: "Spaarrekening", Identifier: "NL02 ABNA 0123 4567 89"},
+		},
+		NetWorth: map[string]NetWorthMonth{
+			"2025-03": {Month: "2025-03", Accounts: map[string]float64{"Betaalrekening": 1500, "Spaarrekening": 12000}},
+			"2025-04": {Month: "2025-04", Accounts: map[string]float64{"Betaalrekening": 1500}, Unknown: []string{"Spaarrekening"}},
+		},
+	}
+	config := TransferConfig{SavingsIBANs: map[string]bool{"NL02ABNA0123456789": true}}
+
+	if liquid, ok := liquidSavings(store, "2025-03", config); !ok || liquid != 12000 {
+		t.Errorf("liquid savings = %.2f, %v, want 12000.00", liquid, ok)
+	}
+	if _, ok := liquidSavings(store, "2025-04", config); ok {
+		t.Error("liquid savings known while the savings balance isn't")
+	}
+	if _, ok := liquidSavings(store, "2025-03", TransferConfig{}); ok {
+		t.Error("liquid savings known without savings accounts")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// snapshotSchemaVersion is bumped whenever the snapshot layout changes.
+// Snapshots without a version predate ledger accounts and documents being
+// saved and can't be used for offline reports. Version 2 added tax rates,
+// contacts, projects, exchange rates, sales invoices and the financial
+// statement of each mutation; version 1 snapshots still load, without them.
+const snapshotSchemaVersion = 2
+
+// Snapshot is everything a report is built from. It is saved after every
+// online run as financial_data_YYYY-MM.json and can be read back with
+// `report --from-snapshot` to rebuild the report without network access.
+type Snapshot struct {
+	SchemaVersion  int                           `json:"schema_version"`
+	PeriodStart    string                        `json:"period_start"`
+	PeriodEnd      string                        `json:"period_end"`
+	LedgerAccounts []LedgerAccount               `json:"ledger_accounts"`
+	TaxRates       []TaxRate                     `json:"tax_rates,omitempty"`
+	Contacts       []Contact                     `json:"contacts,omitempty"`
+	Projects       []Project                     `json:"projects,omitempty"`
+	Mutations      []FinancialMutation           `json:"mutations"`
+	Documents      map[string]Document           `json:"documents"`                // documents referenced by payments, by ID
+	SalesInvoices  map[string]Document           `json:"sales_invoices,omitempty"` // sales invoices referenced by payments, by ID
+	ExchangeRates  map[string]float64            `json:"exchange_rates,omitempty"` // currency → euros, for amounts without a base amount
+	Totals         map[string]map[string]float64 `json:"totals"`                   // account type → account name → total
+}
+
+// snapshotFilename returns the file name used for a period's snapshot
+func snapshotFilename(periodStart time.Time) string {
+	return fmt.Sprintf("financial_data_%s.json", periodStart.Format("2006-01"))
+}
+
+// loadSnapshot reads a snapshot written by an earlier run
+func loadSnapshot(filename string) (*Snapshot, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("unmarshaling snapshot: %w", err)
+	}
+
+	if snap.SchemaVersion == 0 {
+		return nil, fmt.Errorf("%s was written by an older version without ledger accounts and documents; run once online to create a new snapshot", filename)
+	}
+	if snap.SchemaVersion > snapshotSchemaVersion {
+		return nil, fmt.Errorf("%s has schema version %d, this build only understands up to %d", filename, snap.SchemaVersion, snapshotSchemaVersion)
+	}
+	if snap.Documents == nil {
+		snap.Documents = make(map[string]Document)
+	}
+
+	return &snap, nil
+}
+
+// save writes the snapshot as indented JSON
+func (s *Snapshot) save(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling snapshot: %w", err)
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// periodStart parses the snapshot's start date
+func (s *Snapshot) periodStart() time.Time {
+	t, _ := time.Parse("2006-01-02", s.PeriodStart)
+	return t
+}
+
+// periodEnd parses the snapshot's end date
+func (s *Snapshot) periodEnd() time.Time {
+	t, _ := time.Parse("2006-01-02", s.PeriodEnd)
+	return t
+}
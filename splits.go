@@ -0,0 +1,376 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultSplitsFile = "splits.json"
+
+// SplitPart is the share of a transaction booked on one ledger account
+type SplitPart struct {
+	LedgerAccount string  `json:"ledger_account"` // name or ID
+	Percentage    float64 `json:"percentage"`
+}
+
+// SplitRule splits matching uncategorized mutations over several ledger
+// accounts, e.g. 60% business expense and 40% family equity
+type SplitRule struct {
+	Name string `json:"name"`
+	RuleConditions
+	Parts []SplitPart `json:"parts"`
+}
+
+// SplitConfig is the contents of the splits file. Overrides split a single
+// mutation by ID and take precedence over the rules.
+type SplitConfig struct {
+	Rules     []SplitRule            `json:"rules"`
+	Overrides map[string][]SplitPart `json:"overrides"`
+}
+
+// splitShare is a validated split part
+type splitShare struct {
+	account    LedgerAccount
+	percentage float64
+}
+
+// compiledSplitRule is a split rule with its conditions and parts validated
+type compiledSplitRule struct {
+	name       string
+	conditions compiledConditions
+	shares     []splitShare
+}
+
+// splitConfig is the validated splits file
+type splitConfig struct {
+	rules     []compiledSplitRule
+	overrides map[string][]splitShare
+}
+
+// SplitBooking is one part of a planned split
+type SplitBooking struct {
+	Account    LedgerAccount
+	Percentage float64
+	Price      string // in the sign of the mutation amount
+}
+
+// SplitPlan is how an uncategorized mutation will be booked in parts. For a
+// split that was interrupted earlier, Bookings are the parts still missing.
+type SplitPlan struct {
+	Mutation FinancialMutation
+	Source   string // "override" or the rule name
+	Bookings []SplitBooking
+	Booked   int // parts booked before, when finishing an interrupted split
+}
+
+// compileSplitParts resolves the ledger accounts of the parts and checks that
+// they add up to 100%
+func compileSplitParts(parts []SplitPart, accounts []LedgerAccount) ([]splitShare, error) {
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("a split needs at least two parts")
+	}
+
+	var shares []splitShare
+	var total float64
+	for _, part := range parts {
+		if part.Percentage <= 0 {
+			return nil, fmt.Errorf("part %q has percentage %g, it has to be positive", part.LedgerAccount, part.Percentage)
+		}
+		acc, err := resolveBookingAccount(part.LedgerAccount, accounts)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, splitShare{account: acc, percentage: part.Percentage})
+		total += part.Percentage
+	}
+	if math.Abs(total-100) > 0.01 {
+		return nil, fmt.Errorf("parts add up to %g%%, not 100%%", total)
+	}
+	return shares, nil
+}
+
+// loadSplits reads and validates the splits file. A missing file means no splits.
+func loadSplits(filename string, accounts []LedgerAccount) (*splitConfig, error) {
+	config := &splitConfig{overrides: make(map[string][]splitShare)}
+
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading splits file: %w", err)
+	}
+
+	var file SplitConfig
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("unmarshaling splits file: %w", err)
+	}
+
+	for i, rule := range file.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("split %d", i+1)
+		}
+		compiled := compiledSplitRule{name: rule.Name}
+		if compiled.conditions, err = compileConditions(rule.RuleConditions); err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, err)
+		}
+		if compiled.shares, err = compileSplitParts(rule.Parts, accounts); err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, err)
+		}
+		config.rules = append(config.rules, compiled)
+	}
+
+	for mutationID, parts := range file.Overrides {
+		shares, err := compileSplitParts(parts, accounts)
+		if err != nil {
+			return nil, fmt.Errorf("override for %s: %w", mutationID, err)
+		}
+		config.overrides[mutationID] = shares
+	}
+
+	return config, nil
+}
+
+// splitAmount divides an amount over the shares in whole cents. The last
+// share gets what is left, so the parts always add up to the amount.
+func splitAmount(amount string, shares []splitShare) []SplitBooking {
+	var value float64
+	fmt.Sscanf(amount, "%f", &value)
+	totalCents := int64(math.Round(value * 100))
+
+	bookings := make([]SplitBooking, len(shares))
+	var assigned int64
+	for i, share := range shares {
+		cents := int64(math.Round(float64(totalCents) * share.percentage / 100))
+		if i == len(shares)-1 {
+			cents = totalCents - assigned
+		}
+		assigned += cents
+		bookings[i] = SplitBooking{
+			Account:    share.account,
+			Percentage: share.percentage,
+			Price:      fmt.Sprintf("%.2f", float64(cents)/100),
+		}
+	}
+	return bookings
+}
+
+// planSplits decides which uncategorized mutations are split and how: by
+// their override, or else by the first split rule that matches. A mutation
+// that is partly booked with the parts of its split, because an earlier run
+// was interrupted, is planned with the parts still missing.
+func planSplits(config *splitConfig, mutations []FinancialMutation) []SplitPlan {
+	var plans []SplitPlan
+	for _, mut := range mutations {
+		if !isUncategorized(mut) && !isPartlyBooked(mut) {
+			continue
+		}
+
+		shares, source, ok := config.sharesFor(mut)
+		if !ok {
+			continue
+		}
+		bookings, ok := missingBookings(splitAmount(mut.Amount, shares), mut.LedgerAccountBookings)
+		if !ok || len(bookings) == 0 {
+			continue
+		}
+		plans = append(plans, SplitPlan{Mutation: mut, Source: source, Bookings: bookings, Booked: len(mut.LedgerAccountBookings)})
+	}
+	return plans
+}
+
+// sharesFor returns the split of a mutation: its override, or else the first
+// split rule that matches
+func (c *splitConfig) sharesFor(mut FinancialMutation) ([]splitShare, string, bool) {
+	if shares, ok := c.overrides[mut.ID]; ok {
+		return shares, "override", true
+	}
+	for _, rule := range c.rules {
+		if _, ok := rule.conditions.match(mut); ok {
+			return rule.shares, rule.name, true
+		}
+	}
+	return nil, "", false
+}
+
+// isPartlyBooked reports whether a mutation has bookings that don't add up to
+// its amount yet
+func isPartlyBooked(mut FinancialMutation) bool {
+	if len(mut.LedgerAccountBookings) == 0 || len(mut.Payments) > 0 {
+		return false
+	}
+	var amount, booked float64
+	fmt.Sscanf(mut.Amount, "%f", &amount)
+	for _, booking := range mut.LedgerAccountBookings {
+		var price float64
+		fmt.Sscanf(booking.Price, "%f", &price)
+		booked += price
+	}
+	return math.Round(amount*100) != math.Round(booked*100)
+}
+
+// missingBookings removes the planned parts that are already booked. It fails
+// when a booking isn't one of the planned parts, i.e. the mutation was booked
+// some other way.
+func missingBookings(planned []SplitBooking, existing []LedgerAccountBooking) ([]SplitBooking, bool) {
+	done := make([]bool, len(planned))
+	for _, booking := range existing {
+		var price float64
+		fmt.Sscanf(booking.Price, "%f", &price)
+		found := false
+		for i, part := range planned {
+			var partPrice float64
+			fmt.Sscanf(part.Price, "%f", &partPrice)
+			if !done[i] && part.Account.ID == booking.LedgerAccountID && math.Round(partPrice*100) == math.Round(price*100) {
+				done[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+
+	var missing []SplitBooking
+	for i, part := range planned {
+		if !done[i] {
+			missing = append(missing, part)
+		}
+	}
+	return missing, true
+}
+
+// withSplitBookings returns a copy of the mutations with the planned split
+// bookings, as if they had been made
+func withSplitBookings(mutations []FinancialMutation, plans []SplitPlan) []FinancialMutation {
+	byID := make(map[string]SplitPlan)
+	for _, plan := range plans {
+		byID[plan.Mutation.ID] = plan
+	}
+
+	previewed := make([]FinancialMutation, len(mutations))
+	for i, mut := range mutations {
+		previewed[i] = mut
+		plan, ok := byID[mut.ID]
+		if !ok {
+			continue
+		}
+		// Parts booked before an interrupted split stay
+		previewed[i].LedgerAccountBookings = append([]LedgerAccountBooking(nil), mut.LedgerAccountBookings...)
+		for _, booking := range plan.Bookings {
+			previewed[i].LedgerAccountBookings = append(previewed[i].LedgerAccountBookings, LedgerAccountBooking{
+				FinancialMutationID: mut.ID,
+				LedgerAccountID:     booking.Account.ID,
+				Description:         fmt.Sprintf("split: %s %g%%", plan.Source, booking.Percentage),
+				Price:               booking.Price,
+			})
+		}
+	}
+	return previewed
+}
+
+// applySplits books every part of the plans in Moneybird and updates the
+// store with the booked mutations. It returns the number of mutations split.
+// A mutation whose later parts fail is left partly booked and reported; the
+// next run plans the missing parts to finish it.
+func applySplits(client *Client, store *Store, plans []SplitPlan) (int, error) {
+	var split int
+	for _, plan := range plans {
+		for i, booking := range plan.Bookings {
+			description := fmt.Sprintf("%g%% %s", booking.Percentage, plan.Source)
+			mut, err := client.BookOnLedgerAccount(plan.Mutation.ID, booking.Account.ID, booking.Price, description)
+			if err != nil {
+				return split, fmt.Errorf("booking part %d of %s on %s (%d of %d parts booked, run split again to finish): %w",
+					plan.Booked+i+1, plan.Mutation.ID, booking.Account.Name, plan.Booked+i, plan.Booked+len(plan.Bookings), err)
+			}
+			store.PutFinancialMutation(*mut)
+		}
+		split++
+	}
+	return split, nil
+}
+
+// printSplitPlans lists the splits and the total going to business expenses
+// and family equity
+func printSplitPlans(plans []SplitPlan, applied bool) {
+	verb := "would split"
+	if applied {
+		verb = "split"
+	}
+
+	totals := make(map[string]float64)
+	for _, plan := range plans {
+		var parts []string
+		for _, booking := range plan.Bookings {
+			var price float64
+			fmt.Sscanf(booking.Price, "%f", &price)
+			parts = append(parts, fmt.Sprintf("%g%% %s €%.2f", booking.Percentage, booking.Account.Name, price))
+			totals[booking.Account.AccountType] += price
+		}
+		var resumed string
+		if plan.Booked > 0 {
+			resumed = fmt.Sprintf(" (finishing, %d of %d parts booked before)", plan.Booked, plan.Booked+len(plan.Bookings))
+		}
+		fmt.Printf("   %s %s %s → %s%s\n", plan.Source, verb, describeMutation(plan.Mutation), strings.Join(parts, " + "), resumed)
+	}
+	if len(plans) > 0 {
+		fmt.Printf("   Business part: €%.2f, family part: €%.2f\n", totals["expenses"], totals["equity"])
+	}
+}
+
+// runSplit books the planned splits of the uncategorized mutations in the
+// store. Without -yes it only lists them.
+func runSplit(args []string) {
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+	storeFile := fs.String("store", defaultStoreFile, "Local store of synced Moneybird data")
+	splitsFile := fs.String("splits", defaultSplitsFile, "File with split rules and per-transaction overrides")
+	period := fs.String("period", "", "Only split transactions of a month (YYYY-MM, default: all stored)")
+	yes := fs.Bool("yes", false, "Write the split bookings to Moneybird instead of only listing them")
+	fs.Parse(args)
+
+	store, err := OpenStore(*storeFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	config, err := loadSplits(*splitsFile, store.Accounts())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	start, end := "", ""
+	if *period != "" {
+		month, err := time.Parse("2006-01", *period)
+		if err != nil {
+			fmt.Printf("Error: invalid period: %v\n", err)
+			os.Exit(1)
+		}
+		start, end = month.Format("2006-01-02"), month.AddDate(0, 1, -1).Format("2006-01-02")
+	}
+
+	plans := planSplits(config, store.MutationsBetween(start, end))
+	fmt.Printf("%d transactions to split:\n", len(plans))
+	printSplitPlans(plans, false)
+	if !*yes {
+		if len(plans) > 0 {
+			fmt.Println("\nRun again with -yes to write these bookings to Moneybird")
+		}
+		return
+	}
+
+	split, err := applySplits(newClientFromEnv(), store, plans)
+	if saveErr := store.Save(); saveErr != nil {
+		fmt.Printf("Warning: could not save store: %v\n", saveErr)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n✓ Split %d transactions\n", split)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPlanSplitsFinishesInterruptedSplit(t *testing.T) {
+	business := LedgerAccount{ID: "b", Name: "Software", AccountType: "expenses"}
+	family := LedgerAccount{ID: "f", Name: "Boodschappen", AccountType: "equity"}
+	other := LedgerAccount{ID: "o", Name: "Uit eten", AccountType: "equity"}
+	config := &splitConfig{overrides: map[string][]splitShare{
+		"m1": {{business, 50}, {family, 30}, {other, 20}},
+	}}
+
+	mut := FinancialMutation{ID: "m1", State: "unprocessed", Amount: "-100.00"}
+	plans := planSplits(config, []FinancialMutation{mut})
+	if len(plans) != 1 || len(plans[0].Bookings) != 3 || plans[0].Booked != 0 {
+		t.Fatalf("fresh plan = %+v, want three parts", plans)
+	}
+
+	// The first part was booked before the run stopped
+	mut.LedgerAccountBookings = []LedgerAccountBooking{{LedgerAccountID: "b", Price: "-50.00"}}
+	plans = planSplits(config, []FinancialMutation{mut})
+	if len(plans) != 1 || plans[0].Booked != 1 || len(plans[0].Bookings) != 2 {
+		t.Fatalf("resumed plan = %+v, want the two missing parts", plans)
+	}
+	if plans[0].Bookings[0].Account.ID != "f" || plans[0].Bookings[0].Price != "-30.00" ||
+		plans[0].Bookings[1].Account.ID != "o" || plans[0].Bookings[1].Price != "-20.00" {
+		t.Errorf("missing parts = %+v", plans[0].Bookings)
+	}
+
+	// The preview keeps the part that was booked
+	previewed := withSplitBookings([]FinancialMutation{mut}, plans)
+	if n := len(previewed[0].LedgerAccountBookings); n != 3 {
+		t.Errorf("previewed bookings = %d, want 3", n)
+	}
+	if len(mut.LedgerAccountBookings) != 1 {
+		t.Errorf("preview changed the original mutation")
+	}
+
+	// Fully booked, or booked some other way: nothing to do
+	mut.LedgerAccountBookings = append(mut.LedgerAccountBookings,
+		LedgerAccountBooking{LedgerAccountID: "f", Price: "-30.00"},
+		LedgerAccountBooking{LedgerAccountID: "o", Price: "-20.00"})
+	if plans := planSplits(config, []FinancialMutation{mut}); len(plans) != 0 {
+		t.Errorf("fully split mutation planned again: %+v", plans)
+	}
+	mut.LedgerAccountBookings = []LedgerAccountBooking{{LedgerAccountID: "o", Price: "-60.00"}}
+	if plans := planSplits(config, []FinancialMutation{mut}); len(plans) != 0 {
+		t.Errorf("mutation booked by hand planned as a split: %+v", plans)
+	}
+}
@@ -0,0 +1,541 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+)
+
+const (
+	defaultStoreFile = "financial_store.json"
+
+	// previousStoreSuffix is appended to the store path for the copy of the
+	// store as it was before the last sync, see `diff --store`
+	previousStoreSuffix = ".prev"
+)
+
+// Store is a file-based local copy of the administration's ledger accounts,
+// tax rates, contacts, projects, financial accounts, financial mutations
+// (including their payments and bookings) and documents.
+// Records are keyed by ID; a stored record is only replaced by a newer version.
+// It is kept up to date through the synchronization endpoints, see sync.go.
+// It also keeps the balances of the latest bank statements, and what isn't in
+// Moneybird: manually maintained assets and liabilities, the net worth
+// recorded per month and the manual revenue the report was run with.
+type Store struct {
+	path string
+
+	LedgerAccounts     map[string]LedgerAccount     `json:"ledger_accounts"`
+	TaxRates           map[string]TaxRate           `json:"tax_rates"`
+	Contacts           map[string]Contact           `json:"contacts"`
+	Projects           map[string]Project           `json:"projects"`
+	FinancialAccounts  map[string]FinancialAccount  `json:"financial_accounts"`
+	Balances           map[string]KnownBalance      `json:"balances"`     // by financial account ID
+	ManualItems        map[string]ManualItem        `json:"manual_items"` // by lowercase name
+	NetWorth           map[string]NetWorthMonth     `json:"net_worth"`    // by YYYY-MM
+	Revenue            map[string]float64           `json:"revenue"`      // manual revenue by YYYY-MM
+	FinancialMutations map[string]FinancialMutation `json:"financial_mutations"`
+	SyncedPeriods      Periods                      `json:"synced_periods"` // periods whose mutations are all stored
+	Documents          map[string]Document          `json:"documents"`
+	SalesInvoices      map[string]Document          `json:"sales_invoices"`
+	LastSync           time.Time                    `json:"last_sync"`
+}
+
+// Period is a range of dates, both ends included
+type Period struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Periods are non-overlapping periods, sorted by start
+type Periods []Period
+
+// add returns the periods with [start, end] added, merging the periods it
+// overlaps or touches
+func (p Periods) add(start, end string) Periods {
+	merged := append(append(Periods{}, p...), Period{start, end})
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Start < merged[j].Start
+	})
+
+	result := Periods{}
+	for _, period := range merged {
+		if n := len(result); n > 0 && period.Start <= nextDay(result[n-1].End) {
+			result[n-1].End = max(result[n-1].End, period.End)
+			continue
+		}
+		result = append(result, period)
+	}
+	return result
+}
+
+// covers reports whether every day in [start, end] is in one of the periods
+func (p Periods) covers(start, end string) bool {
+	for _, period := range p {
+		if period.Start <= start && end <= period.End {
+			return true
+		}
+	}
+	return false
+}
+
+// nextDay returns the day after a YYYY-MM-DD date
+func nextDay(date string) string {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return day.AddDate(0, 0, 1).Format("2006-01-02")
+}
+
+// SyncStats counts what changed in the store during a sync
+type SyncStats struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Removed   int
+}
+
+// String formats the stats for console output
+func (s SyncStats) String() string {
+	return fmt.Sprintf("%d added, %d updated, %d removed, %d unchanged", s.Added, s.Updated, s.Removed, s.Unchanged)
+}
+
+// record updates the counters for the outcome of a single put
+func (s *SyncStats) record(added, updated bool) {
+	switch {
+	case added:
+		s.Added++
+	case updated:
+		s.Updated++
+	default:
+		s.Unchanged++
+	}
+}
+
+// OpenStore loads the store from disk, starting empty if the file doesn't exist yet
+func OpenStore(path string) (*Store, error) {
+	store := &Store{
+		path:               path,
+		LedgerAccounts:     make(map[string]LedgerAccount),
+		TaxRates:           make(map[string]TaxRate),
+		Contacts:           make(map[string]Contact),
+		Projects:           make(map[string]Project),
+		FinancialAccounts:  make(map[string]FinancialAccount),
+		Balances:           make(map[string]KnownBalance),
+		ManualItems:        make(map[string]ManualItem),
+		NetWorth:           make(map[string]NetWorthMonth),
+		Revenue:            make(map[string]float64),
+		FinancialMutations: make(map[string]FinancialMutation),
+		Documents:          make(map[string]Document),
+		SalesInvoices:      make(map[string]Document),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading store: %w", err)
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("unmarshaling store: %w", err)
+	}
+	if store.LedgerAccounts == nil {
+		store.LedgerAccounts = make(map[string]LedgerAccount)
+	}
+	if store.TaxRates == nil {
+		store.TaxRates = make(map[string]TaxRate)
+	}
+	if store.Contacts == nil {
+		store.Contacts = make(map[string]Contact)
+	}
+	if store.Projects == nil {
+		store.Projects = make(map[string]Project)
+	}
+	if store.FinancialAccounts == nil {
+		store.FinancialAccounts = make(map[string]FinancialAccount)
+	}
+	if store.Balances == nil {
+		store.Balances = make(map[string]KnownBalance)
+	}
+	if store.ManualItems == nil {
+		store.ManualItems = make(map[string]ManualItem)
+	}
+	if store.NetWorth == nil {
+		store.NetWorth = make(map[string]NetWorthMonth)
+	}
+	if store.Revenue == nil {
+		store.Revenue = make(map[string]float64)
+	}
+	if store.FinancialMutations == nil {
+		store.FinancialMutations = make(map[string]FinancialMutation)
+	}
+	if store.Documents == nil {
+		store.Documents = make(map[string]Document)
+	}
+	if store.SalesInvoices == nil {
+		store.SalesInvoices = make(map[string]Document)
+	}
+
+	return store, nil
+}
+
+// Save writes the store to disk. It writes to a temporary file and renames it
+// over the store, so an interrupted run never leaves a truncated or missing
+// store behind.
+func (s *Store) Save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshaling store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".store-*.json")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing store: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// SaveSynced saves the store after a sync. The store as it was on disk before
+// the sync is kept next to it for `diff --store`; saves by other commands
+// leave that copy alone.
+func (s *Store) SaveSynced() error {
+	s.LastSync = time.Now()
+	if err := s.keepPrevious(); err != nil {
+		return fmt.Errorf("keeping previous store: %w", err)
+	}
+	return s.Save()
+}
+
+// keepPrevious puts the current store file at the previous store path. It
+// links the file when possible and copies it otherwise; either way the
+// previous copy is replaced in a single rename and the store itself stays
+// in place.
+func (s *Store) keepPrevious() error {
+	prev := s.path + previousStoreSuffix
+	tmp := prev + ".tmp"
+	os.Remove(tmp)
+
+	if err := os.Link(s.path, tmp); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		data, err := os.ReadFile(s.path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, prev)
+}
+
+// isNewer reports whether a fetched record replaces the stored one. Moneybird
+// versions are the time of the last update, so a stale copy never replaces a
+// newer one; records without one fall back to UpdatedAt.
+func isNewer(storedVersion, version int64, storedUpdatedAt, updatedAt time.Time) bool {
+	if storedVersion != 0 && version != 0 {
+		return version > storedVersion
+	}
+	return updatedAt.After(storedUpdatedAt)
+}
+
+// PutLedgerAccount stores an account unless the stored copy is at least as recent.
+// It reports whether the account was added or updated.
+func (s *Store) PutLedgerAccount(acc LedgerAccount) (added, updated bool) {
+	existing, ok := s.LedgerAccounts[acc.ID]
+	if ok && !isNewer(existing.Version, acc.Version, existing.UpdatedAt, acc.UpdatedAt) {
+		return false, false
+	}
+	s.LedgerAccounts[acc.ID] = acc
+	return !ok, ok
+}
+
+// PutFinancialMutation stores a mutation unless the stored copy is at least as recent.
+// It reports whether the mutation was added or updated.
+func (s *Store) PutFinancialMutation(mut FinancialMutation) (added, updated bool) {
+	existing, ok := s.FinancialMutations[mut.ID]
+	if ok && !isNewer(existing.Version, mut.Version, existing.UpdatedAt, mut.UpdatedAt) {
+		return false, false
+	}
+	s.FinancialMutations[mut.ID] = mut
+	return !ok, ok
+}
+
+// PutDocument stores a document unless the stored copy is at least as recent.
+// It reports whether the document was added or updated.
+func (s *Store) PutDocument(doc Document) (added, updated bool) {
+	existing, ok := s.Documents[doc.ID]
+	if ok && !isNewer(existing.Version, doc.Version, existing.UpdatedAt, doc.UpdatedAt) {
+		return false, false
+	}
+	s.Documents[doc.ID] = doc
+	return !ok, ok
+}
+
+// PutSalesInvoice stores a sales invoice unless the stored copy is at least as
+// recent. It reports whether the invoice was added or updated.
+func (s *Store) PutSalesInvoice(invoice Document) (added, updated bool) {
+	existing, ok := s.SalesInvoices[invoice.ID]
+	if ok && !isNewer(existing.Version, invoice.Version, existing.UpdatedAt, invoice.UpdatedAt) {
+		return false, false
+	}
+	s.SalesInvoices[invoice.ID] = invoice
+	return !ok, ok
+}
+
+// SyncLedgerAccounts stores a full list of ledger accounts, removing accounts
+// that no longer exist
+func (s *Store) SyncLedgerAccounts(accounts []LedgerAccount) SyncStats {
+	var stats SyncStats
+	seen := make(map[string]bool)
+	for _, acc := range accounts {
+		seen[acc.ID] = true
+		stats.record(s.PutLedgerAccount(acc))
+	}
+	for id := range s.LedgerAccounts {
+		if !seen[id] {
+			delete(s.LedgerAccounts, id)
+			stats.Removed++
+		}
+	}
+	return stats
+}
+
+// SyncTaxRates stores a full list of tax rates, removing rates that no longer
+// exist. Tax rates have no version, so UpdatedAt decides what changed.
+func (s *Store) SyncTaxRates(rates []TaxRate) SyncStats {
+	var stats SyncStats
+	seen := make(map[string]bool)
+	for _, rate := range rates {
+		seen[rate.ID] = true
+		existing, ok := s.TaxRates[rate.ID]
+		if ok && !isNewer(0, 0, existing.UpdatedAt, rate.UpdatedAt) {
+			stats.record(false, false)
+			continue
+		}
+		s.TaxRates[rate.ID] = rate
+		stats.record(!ok, ok)
+	}
+	for id := range s.TaxRates {
+		if !seen[id] {
+			delete(s.TaxRates, id)
+			stats.Removed++
+		}
+	}
+	return stats
+}
+
+// SyncFinancialAccounts stores a full list of financial accounts, removing
+// accounts that no longer exist. UpdatedAt decides what changed.
+func (s *Store) SyncFinancialAccounts(accounts []FinancialAccount) SyncStats {
+	var stats SyncStats
+	seen := make(map[string]bool)
+	for _, account := range accounts {
+		seen[account.ID] = true
+		existing, ok := s.FinancialAccounts[account.ID]
+		if ok && !isNewer(0, 0, existing.UpdatedAt, account.UpdatedAt) {
+			stats.record(false, false)
+			continue
+		}
+		s.FinancialAccounts[account.ID] = account
+		stats.record(!ok, ok)
+	}
+	for id := range s.FinancialAccounts {
+		if !seen[id] {
+			delete(s.FinancialAccounts, id)
+			stats.Removed++
+		}
+	}
+	return stats
+}
+
+// SyncProjects stores a full list of projects, removing projects that no
+// longer exist. Projects have no version or update time, so they are compared.
+func (s *Store) SyncProjects(projects []Project) SyncStats {
+	var stats SyncStats
+	seen := make(map[string]bool)
+	for _, project := range projects {
+		seen[project.ID] = true
+		existing, ok := s.Projects[project.ID]
+		if ok && reflect.DeepEqual(existing, project) {
+			stats.record(false, false)
+			continue
+		}
+		s.Projects[project.ID] = project
+		stats.record(!ok, ok)
+	}
+	for id := range s.Projects {
+		if !seen[id] {
+			delete(s.Projects, id)
+			stats.Removed++
+		}
+	}
+	return stats
+}
+
+// SyncMutationsBetween stores the complete list of mutations for the period
+// [start, end], removing stored mutations in that period that no longer exist
+func (s *Store) SyncMutationsBetween(start, end string, mutations []FinancialMutation) SyncStats {
+	var stats SyncStats
+	seen := make(map[string]bool)
+	for _, mut := range mutations {
+		seen[mut.ID] = true
+		stats.record(s.PutFinancialMutation(mut))
+	}
+	for id, mut := range s.FinancialMutations {
+		if mut.Date >= start && mut.Date <= end && !seen[id] {
+			delete(s.FinancialMutations, id)
+			stats.Removed++
+		}
+	}
+	return stats
+}
+
+// HasDocument reports whether a document is stored
+func (s *Store) HasDocument(id string) bool {
+	_, ok := s.Documents[id]
+	return ok
+}
+
+// TaxRateList returns all stored tax rates, sorted by name
+func (s *Store) TaxRateList() []TaxRate {
+	rates := make([]TaxRate, 0, len(s.TaxRates))
+	for _, rate := range s.TaxRates {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Name < rates[j].Name
+	})
+	return rates
+}
+
+// FinancialAccountList returns all stored financial accounts, sorted by name
+func (s *Store) FinancialAccountList() []FinancialAccount {
+	accounts := make([]FinancialAccount, 0, len(s.FinancialAccounts))
+	for _, account := range s.FinancialAccounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name < accounts[j].Name
+	})
+	return accounts
+}
+
+// ProjectList returns all stored projects, sorted by name
+func (s *Store) ProjectList() []Project {
+	projects := make([]Project, 0, len(s.Projects))
+	for _, project := range s.Projects {
+		projects = append(projects, project)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Name < projects[j].Name
+	})
+	return projects
+}
+
+// Accounts returns all stored ledger accounts, sorted by name
+func (s *Store) Accounts() []LedgerAccount {
+	accounts := make([]LedgerAccount, 0, len(s.LedgerAccounts))
+	for _, acc := range s.LedgerAccounts {
+		accounts = append(accounts, acc)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name < accounts[j].Name
+	})
+	return accounts
+}
+
+// MutationsBetween returns the stored mutations dated within [start, end]
+// (both YYYY-MM-DD), sorted by date and ID. Empty bounds are open.
+func (s *Store) MutationsBetween(start, end string) []FinancialMutation {
+	var mutations []FinancialMutation
+	for _, mut := range s.FinancialMutations {
+		if (start == "" || mut.Date >= start) && (end == "" || mut.Date <= end) {
+			mutations = append(mutations, mut)
+		}
+	}
+	sortMutations(mutations)
+	return mutations
+}
+
+// sortMutations orders mutations by date, then ID, so output is deterministic
+func sortMutations(mutations []FinancialMutation) {
+	sort.Slice(mutations, func(i, j int) bool {
+		if mutations[i].Date != mutations[j].Date {
+			return mutations[i].Date < mutations[j].Date
+		}
+		return mutations[i].ID < mutations[j].ID
+	})
+}
+
+// Snapshot returns the stored data for the period [start, end] (YYYY-MM-DD,
+// empty bounds are open) with the documents its payments reference
+func (s *Store) Snapshot(start, end string) *Snapshot {
+	mutations := s.MutationsBetween(start, end)
+
+	documents := make(map[string]Document)
+	salesInvoices := make(map[string]Document)
+	ibans := make(map[string]bool)
+	for _, mut := range mutations {
+		ibans[normalizeIBAN(mut.ContraAccountNumber)] = true
+		for _, payment := range mut.Payments {
+			if doc, ok := s.Documents[payment.InvoiceID]; ok && payment.InvoiceType == "Document" {
+				documents[doc.ID] = doc
+			}
+			if invoice, ok := s.SalesInvoices[payment.InvoiceID]; ok && payment.InvoiceType == "SalesInvoice" {
+				salesInvoices[invoice.ID] = invoice
+			}
+		}
+	}
+
+	// Only the contacts the period's documents and counterparties refer to
+	contactIDs := make(map[string]bool)
+	for _, doc := range documents {
+		contactIDs[doc.ContactID] = true
+	}
+	var contacts []Contact
+	for _, contact := range s.Contacts {
+		if contactIDs[contact.ID] || (contact.SepaIBAN != "" && ibans[normalizeIBAN(contact.SepaIBAN)]) {
+			contacts = append(contacts, contact)
+		}
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		return contacts[i].ID < contacts[j].ID
+	})
+
+	return &Snapshot{
+		SchemaVersion:  snapshotSchemaVersion,
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		LedgerAccounts: s.Accounts(),
+		TaxRates:       s.TaxRateList(),
+		Projects:       s.ProjectList(),
+		Contacts:       contacts,
+		Mutations:      mutations,
+		Documents:      documents,
+		SalesInvoices:  salesInvoices,
+	}
+}
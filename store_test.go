@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreSaveKeepsPreviousOnlyOnSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	store, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.ManualItems["car"] = ManualItem{Name: "Car", Kind: "asset"}
+	if err := store.SaveSynced(); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if _, err := os.Stat(path + previousStoreSuffix); !os.IsNotExist(err) {
+		t.Fatalf("first sync left a previous store: %v", err)
+	}
+
+	store.ManualItems["house"] = ManualItem{Name: "House", Kind: "asset"}
+	if err := store.SaveSynced(); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+
+	// A save by another command keeps the copy from before the last sync
+	store.ManualItems["loan"] = ManualItem{Name: "Loan", Kind: "liability"}
+	if err := store.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	prev, err := OpenStore(path + previousStoreSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prev.ManualItems) != 1 {
+		t.Errorf("previous store has %d items, want the 1 from before the last sync", len(prev.ManualItems))
+	}
+	current, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current.ManualItems) != 3 {
+		t.Errorf("store has %d items, want 3", len(current.ManualItems))
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".store-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
+
+func TestPeriodsMergeAdjacentSyncs(t *testing.T) {
+	periods := Periods{}.
+		add("2025-03-01", "2025-03-31").
+		add("2025-01-01", "2025-01-31").
+		add("2025-02-01", "2025-02-28").
+		add("2025-06-01", "2025-06-30")
+
+	want := Periods{{"2025-01-01", "2025-03-31"}, {"2025-06-01", "2025-06-30"}}
+	if len(periods) != len(want) || periods[0] != want[0] || periods[1] != want[1] {
+		t.Errorf("periods = %v, want %v", periods, want)
+	}
+	if !periods.covers("2025-01-15", "2025-03-10") {
+		t.Error("January to March not covered after syncing each month")
+	}
+	if periods.covers("2025-03-15", "2025-06-10") {
+		t.Error("April and May covered without being synced")
+	}
+}
+
+func TestPutKeepsTheMostRecentCopy(t *testing.T) {
+	store, err := OpenStore(t.TempDir() + "/store.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if added, _ := store.PutDocument(Document{ID: "doc", Version: 200, State: "open"}); !added {
+		t.Fatal("document not added")
+	}
+	if _, updated := store.PutDocument(Document{ID: "doc", Version: 100, State: "new"}); updated || store.Documents["doc"].State != "open" {
+		t.Errorf("an older version replaced the stored document: %+v", store.Documents["doc"])
+	}
+	if _, updated := store.PutDocument(Document{ID: "doc", Version: 200, State: "open"}); updated {
+		t.Error("the same version counted as an update")
+	}
+	if _, updated := store.PutDocument(Document{ID: "doc", Version: 300, State: "paid"}); !updated || store.Documents["doc"].State != "paid" {
+		t.Errorf("a newer version didn't replace the stored document: %+v", store.Documents["doc"])
+	}
+}
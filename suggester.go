@@ -0,0 +1,295 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// defaultAcceptConfidence is the confidence the accept command needs by default
+	defaultAcceptConfidence = 0.9
+
+	// maxMessageTokens limits how many words of the description are used
+	maxMessageTokens = 20
+)
+
+// Suggestion is a ledger account proposed for an uncategorized mutation
+type Suggestion struct {
+	Account    LedgerAccount
+	Confidence float64 // 0 to 1
+}
+
+// suggester is a naive Bayes classifier trained on the booked mutations in the
+// history. It runs locally and is cheap enough to train on every run.
+type suggester struct {
+	accounts      map[string]LedgerAccount
+	classCount    map[string]int            // ledger account ID → training mutations
+	featureCount  map[string]map[string]int // ledger account ID → feature → count
+	featureTotal  map[string]int            // ledger account ID → total features
+	vocabulary    map[string]bool
+	trainingCount int
+}
+
+// mutationFeatures describes a mutation for the suggester: its counterparty,
+// the words of its description, its transaction code and an amount bucket
+func mutationFeatures(mut FinancialMutation) []string {
+	var features []string
+	if iban := normalizeIBAN(mut.ContraAccountNumber); iban != "" {
+		features = append(features, "iban:"+iban)
+	}
+	if name := normalizeMerchantName(mut.ContraAccountName); name != "" {
+		features = append(features, "name:"+name)
+	}
+	if mut.Code != "" {
+		features = append(features, "code:"+mut.Code)
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(mut.Message), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if len(word) < 3 || seen[word] || len(seen) == maxMessageTokens {
+			continue
+		}
+		seen[word] = true
+		features = append(features, "word:"+word)
+	}
+
+	// Amounts are bucketed by order of magnitude: €8-16, €16-32, ...
+	var amount float64
+	fmt.Sscanf(mut.Amount, "%f", &amount)
+	sign := "out"
+	if amount > 0 {
+		sign = "in"
+	}
+	features = append(features, fmt.Sprintf("amount:%s:%d", sign, int(math.Log2(math.Abs(amount)+1))))
+
+	return features
+}
+
+// trainingLabel returns the equity or expenses account a booked mutation is
+// mostly booked on
+func trainingLabel(mut FinancialMutation, accounts map[string]LedgerAccount) (string, bool) {
+	var label string
+	var largest float64
+	for _, booking := range mut.LedgerAccountBookings {
+		acc, ok := accounts[booking.LedgerAccountID]
+		if !ok || (acc.AccountType != "equity" && acc.AccountType != "expenses") {
+			continue
+		}
+		var price float64
+		fmt.Sscanf(booking.Price, "%f", &price)
+		if label == "" || math.Abs(price) > largest {
+			label, largest = acc.ID, math.Abs(price)
+		}
+	}
+	return label, label != ""
+}
+
+// trainSuggester learns from every mutation in the history that is booked on
+// an equity or expenses account
+func trainSuggester(history []FinancialMutation, accounts []LedgerAccount) *suggester {
+	s := &suggester{
+		accounts:     make(map[string]LedgerAccount),
+		classCount:   make(map[string]int),
+		featureCount: make(map[string]map[string]int),
+		featureTotal: make(map[string]int),
+		vocabulary:   make(map[string]bool),
+	}
+	for _, acc := range accounts {
+		s.accounts[acc.ID] = acc
+	}
+
+	for _, mut := range history {
+		label, ok := trainingLabel(mut, s.accounts)
+		if !ok {
+			continue
+		}
+		s.trainingCount++
+		s.classCount[label]++
+		if s.featureCount[label] == nil {
+			s.featureCount[label] = make(map[string]int)
+		}
+		for _, feature := range mutationFeatures(mut) {
+			s.featureCount[label][feature]++
+			s.featureTotal[label]++
+			s.vocabulary[feature] = true
+		}
+	}
+
+	return s
+}
+
+// suggest scores every ledger account seen in training and returns them best
+// first. The confidence is the posterior probability, scaled down by the
+// share of the mutation's features that occurred in training, so a mutation
+// unlike anything seen before never gets a confident suggestion.
+func (s *suggester) suggest(mut FinancialMutation) []Suggestion {
+	if s.trainingCount == 0 {
+		return nil
+	}
+
+	features := mutationFeatures(mut)
+	var known int
+	for _, feature := range features {
+		if s.vocabulary[feature] {
+			known++
+		}
+	}
+	coverage := float64(known) / float64(len(features))
+
+	vocabulary := float64(len(s.vocabulary))
+	logScores := make(map[string]float64)
+	best := math.Inf(-1)
+	for label, count := range s.classCount {
+		score := math.Log(float64(count) / float64(s.trainingCount))
+		for _, feature := range features {
+			score += math.Log((float64(s.featureCount[label][feature]) + 1) / (float64(s.featureTotal[label]) + vocabulary))
+		}
+		logScores[label] = score
+		best = math.Max(best, score)
+	}
+
+	// Normalize in log space to avoid underflow
+	var sum float64
+	for _, score := range logScores {
+		sum += math.Exp(score - best)
+	}
+
+	suggestions := make([]Suggestion, 0, len(logScores))
+	for label, score := range logScores {
+		suggestions = append(suggestions, Suggestion{
+			Account:    s.accounts[label],
+			Confidence: math.Exp(score-best) / sum * coverage,
+		})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		return suggestions[i].Account.Name < suggestions[j].Account.Name
+	})
+	return suggestions
+}
+
+// best returns the top suggestion for a mutation, if there is any
+func (s *suggester) best(mut FinancialMutation) (Suggestion, bool) {
+	suggestions := s.suggest(mut)
+	if len(suggestions) == 0 {
+		return Suggestion{}, false
+	}
+	return suggestions[0], true
+}
+
+// printUncategorized lists the uncategorized mutations with their suggestion
+func printUncategorized(mutations []FinancialMutation, model *suggester) {
+	var count int
+	for _, mut := range mutations {
+		if !isUncategorized(mut) {
+			continue
+		}
+		count++
+		fmt.Printf("   %s", describeMutation(mut))
+		if suggestion, ok := model.best(mut); ok {
+			fmt.Printf("  → %s (%.0f%%)", suggestion.Account.Name, suggestion.Confidence*100)
+		}
+		fmt.Println()
+	}
+	if count == 0 {
+		fmt.Println("   Everything is categorized")
+	}
+}
+
+// runAccept books the uncategorized mutations in the store whose suggestion
+// is confident enough. Without -yes it only lists what it would book.
+func runAccept(args []string) {
+	fs := flag.NewFlagSet("accept", flag.ExitOnError)
+	storeFile := fs.String("store", defaultStoreFile, "Local store of synced Moneybird data")
+	minConfidence := fs.Float64("min-confidence", defaultAcceptConfidence, "Minimum confidence (0-1) of the suggestions to accept")
+	period := fs.String("period", "", "Only accept transactions of a month (YYYY-MM, default: all stored)")
+	yes := fs.Bool("yes", false, "Book the suggestions in Moneybird instead of only listing them")
+	splitsFile := fs.String("splits", defaultSplitsFile, "File with split rules and overrides; transactions to be split are left to the split command")
+	fs.Parse(args)
+
+	store, err := OpenStore(*storeFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	start, end := "", ""
+	if *period != "" {
+		month, err := time.Parse("2006-01", *period)
+		if err != nil {
+			fmt.Printf("Error: invalid period: %v\n", err)
+			os.Exit(1)
+		}
+		start, end = month.Format("2006-01-02"), month.AddDate(0, 1, -1).Format("2006-01-02")
+	}
+
+	splits, err := loadSplits(*splitsFile, store.Accounts())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	model := trainSuggester(store.MutationsBetween("", ""), store.Accounts())
+	fmt.Printf("Trained on %d categorized transactions\n", model.trainingCount)
+
+	// Transactions that are going to be split, and internal transfers, aren't
+	// booked on a single category
+	candidates := store.MutationsBetween(start, end)
+	skip := transferIDs(detectTransfers(store.MutationsBetween("", ""), transferConfigFromEnv()))
+	for _, plan := range planSplits(splits, candidates) {
+		skip[plan.Mutation.ID] = true
+	}
+
+	type acceptance struct {
+		mut        FinancialMutation
+		suggestion Suggestion
+	}
+	var accepted []acceptance
+	for _, mut := range candidates {
+		if !isUncategorized(mut) || skip[mut.ID] {
+			continue
+		}
+		if suggestion, ok := model.best(mut); ok && suggestion.Confidence >= *minConfidence {
+			accepted = append(accepted, acceptance{mut, suggestion})
+		}
+	}
+
+	fmt.Printf("%d suggestions with at least %.0f%% confidence:\n", len(accepted), *minConfidence*100)
+	for _, a := range accepted {
+		fmt.Printf("   %s → %s (%.0f%%)\n", describeMutation(a.mut), a.suggestion.Account.Name, a.suggestion.Confidence*100)
+	}
+	if !*yes {
+		if len(accepted) > 0 {
+			fmt.Println("\nRun again with -yes to book these in Moneybird")
+		}
+		return
+	}
+
+	client := newClientFromEnv()
+	var booked int
+	for _, a := range accepted {
+		mut, err := client.BookOnLedgerAccount(a.mut.ID, a.suggestion.Account.ID, a.mut.Amount, "")
+		if err != nil {
+			fmt.Printf("   Error booking %s: %v\n", a.mut.ID, err)
+			continue
+		}
+		store.PutFinancialMutation(*mut)
+		booked++
+	}
+
+	if err := store.Save(); err != nil {
+		fmt.Printf("Warning: could not save store: %v\n", err)
+	}
+	fmt.Printf("\n✓ Booked %d of %d transactions\n", booked, len(accepted))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSuggesterConfidence(t *testing.T) {
+	accounts := []LedgerAccount{
+		{ID: "food", Name: "Boodschappen", AccountType: "equity"},
+		{ID: "car", Name: "Auto", AccountType: "equity"},
+		{ID: "sales", Name: "Omzet", AccountType: "revenue"},
+	}
+	booked := func(id, name, message, amount, account string) FinancialMutation {
+		return FinancialMutation{ID: id, ContraAccountName: name, Message: message, Amount: amount,
+			LedgerAccountBookings: []LedgerAccountBooking{{LedgerAccountID: account, Price: amount}}}
+	}
+
+	var history []FinancialMutation
+	for i := 0; i < 10; i++ {
+		history = append(history,
+			booked(fmt.Sprintf("j%d", i), "Jumbo Utrecht", "Betaalautomaat boodschappen", "-45.00", "food"),
+			booked(fmt.Sprintf("s%d", i), "Shell Station", "Betaalautomaat tanken", "-70.00", "car"),
+			booked(fmt.Sprintf("r%d", i), "Klant BV", "Factuur", "1210.00", "sales"))
+	}
+	model := trainSuggester(history, accounts)
+
+	// Revenue bookings aren't learned
+	if _, ok := model.classCount["sales"]; ok || model.trainingCount != 20 {
+		t.Errorf("trained on %d mutations with classes %v, want only the equity bookings", model.trainingCount, model.classCount)
+	}
+
+	suggestion, ok := model.best(FinancialMutation{ContraAccountName: "Jumbo Utrecht", Message: "Betaalautomaat boodschappen", Amount: "-38.00"})
+	if !ok || suggestion.Account.ID != "food" || suggestion.Confidence < defaultAcceptConfidence {
+		t.Errorf("suggestion for a Jumbo charge = %+v, want Boodschappen with high confidence", suggestion)
+	}
+
+	// Nothing like it was seen before, so no confident suggestion
+	suggestion, _ = model.best(FinancialMutation{ContraAccountName: "Tandarts", Message: "Controle", Amount: "1.50"})
+	if suggestion.Confidence >= 0.5 {
+		t.Errorf("suggestion for an unknown payee = %+v, want a low confidence", suggestion)
+	}
+
+	if suggestions := trainSuggester(nil, accounts).suggest(history[0]); suggestions != nil {
+		t.Errorf("suggestions without history = %v, want none", suggestions)
+	}
+}
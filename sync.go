@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// syncBatchSize is the maximum number of IDs the synchronization POST endpoints accept
+const syncBatchSize = 100
+
+// SyncVersion is an entry returned by a synchronization endpoint: the ID of a
+// record and its current version
+type SyncVersion struct {
+	ID      string `json:"id"`
+	Version int64  `json:"version"`
+}
+
+// GetSyncVersions lists the IDs and versions of all records of a resource, e.g.
+// "financial_mutations" or "documents/receipts". The filter is optional.
+func (c *Client) GetSyncVersions(resource, filter string) ([]SyncVersion, error) {
+	endpoint := resource + "/synchronization.json"
+	if filter != "" {
+		endpoint += "?filter=" + url.QueryEscape(filter)
+	}
+
+	body, err := c.doRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []SyncVersion
+	if err := json.Unmarshal(body, &versions); err != nil {
+		return nil, fmt.Errorf("unmarshaling response: %w", err)
+	}
+
+	return versions, nil
+}
+
+// fetchByIDs fetches full records of a resource through its synchronization
+// endpoint, in batches of syncBatchSize. Batches are fetched concurrently and
+// returned in the order of the IDs.
+func fetchByIDs[T any](c *Client, resource string, ids []string) ([]T, error) {
+	batches := make([][]T, (len(ids)+syncBatchSize-1)/syncBatchSize)
+	err := forEachConcurrent(len(batches), c.workers, func(i int) error {
+		start := i * syncBatchSize
+		end := min(start+syncBatchSize, len(ids))
+
+		requestBody := map[string]interface{}{
+			"ids": ids[start:end],
+		}
+
+		body, err := c.doRequest("POST", resource+"/synchronization.json", requestBody)
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal(body, &batches[i]); err != nil {
+			return fmt.Errorf("unmarshaling response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []T
+	for _, batch := range batches {
+		records = append(records, batch...)
+	}
+	return records, nil
+}
+
+// GetFinancialMutationsByIDs fetches full financial mutations by ID
+func (c *Client) GetFinancialMutationsByIDs(ids []string) ([]FinancialMutation, error) {
+	return fetchByIDs[FinancialMutation](c, "financial_mutations", ids)
+}
+
+// GetLedgerAccountsByIDs fetches full ledger accounts by ID
+func (c *Client) GetLedgerAccountsByIDs(ids []string) ([]LedgerAccount, error) {
+	return fetchByIDs[LedgerAccount](c, "ledger_accounts", ids)
+}
+
+// GetContactsByIDs fetches full contacts by ID
+func (c *Client) GetContactsByIDs(ids []string) ([]Contact, error) {
+	return fetchByIDs[Contact](c, "contacts", ids)
+}
+
+// syncPlan is the outcome of comparing remote versions with the local ones
+type syncPlan struct {
+	fetch     []string // new or changed records
+	removed   []string // stored records that no longer exist remotely
+	added     int
+	unchanged int
+}
+
+// planSync compares remote versions with the versions of the stored records in
+// scope. Records only stored locally are considered deleted.
+func planSync(remote []SyncVersion, local map[string]int64) syncPlan {
+	var plan syncPlan
+	seen := make(map[string]bool)
+
+	for _, rv := range remote {
+		seen[rv.ID] = true
+		version, ok := local[rv.ID]
+		switch {
+		case !ok:
+			plan.fetch = append(plan.fetch, rv.ID)
+			plan.added++
+		case version != rv.Version:
+			plan.fetch = append(plan.fetch, rv.ID)
+		default:
+			plan.unchanged++
+		}
+	}
+
+	for id := range local {
+		if !seen[id] {
+			plan.removed = append(plan.removed, id)
+		}
+	}
+
+	sort.Strings(plan.fetch)
+	sort.Strings(plan.removed)
+	return plan
+}
+
+// stats converts a plan into the counters shown to the user
+func (p syncPlan) stats() SyncStats {
+	return SyncStats{
+		Added:     p.added,
+		Updated:   len(p.fetch) - p.added,
+		Unchanged: p.unchanged,
+		Removed:   len(p.removed),
+	}
+}
+
+// syncLedgerAccounts brings the stored ledger accounts up to date, fetching
+// only the accounts that were added or changed
+func syncLedgerAccounts(client *Client, store *Store) (SyncStats, error) {
+	remote, err := client.GetSyncVersions("ledger_accounts", "")
+	if err != nil {
+		return SyncStats{}, err
+	}
+
+	local := make(map[string]int64)
+	for id, acc := range store.LedgerAccounts {
+		local[id] = acc.Version
+	}
+
+	plan := planSync(remote, local)
+	accounts, err := client.GetLedgerAccountsByIDs(plan.fetch)
+	if err != nil {
+		return SyncStats{}, err
+	}
+
+	for _, acc := range accounts {
+		store.PutLedgerAccount(acc)
+	}
+	for _, id := range plan.removed {
+		delete(store.LedgerAccounts, id)
+	}
+
+	return plan.stats(), nil
+}
+
+// syncContacts brings the stored contacts up to date, fetching only the
+// contacts that were added or changed
+func syncContacts(client *Client, store *Store) (SyncStats, error) {
+	remote, err := client.GetSyncVersions("contacts", "")
+	if err != nil {
+		return SyncStats{}, err
+	}
+
+	local := make(map[string]int64)
+	for id, contact := range store.Contacts {
+		local[id] = contact.Version
+	}
+
+	plan := planSync(remote, local)
+	contacts, err := client.GetContactsByIDs(plan.fetch)
+	if err != nil {
+		return SyncStats{}, err
+	}
+
+	for _, contact := range contacts {
+		store.Contacts[contact.ID] = contact
+	}
+	for _, id := range plan.removed {
+		delete(store.Contacts, id)
+	}
+
+	return plan.stats(), nil
+}
+
+// syncFinancialMutations brings the stored mutations dated within [start, end]
+// up to date, fetching only the mutations that were added or changed
+func syncFinancialMutations(client *Client, store *Store, start, end string) (SyncStats, error) {
+	remote, err := client.GetSyncVersions("financial_mutations", fmt.Sprintf("period:%s..%s", start, end))
+	if err != nil {
+		return SyncStats{}, err
+	}
+
+	local := make(map[string]int64)
+	for _, mut := range store.MutationsBetween(start, end) {
+		local[mut.ID] = mut.Version
+	}
+
+	plan := planSync(remote, local)
+	mutations, err := client.GetFinancialMutationsByIDs(plan.fetch)
+	if err != nil {
+		return SyncStats{}, err
+	}
+
+	for _, mut := range mutations {
+		store.PutFinancialMutation(mut)
+	}
+	for _, id := range plan.removed {
+		delete(store.FinancialMutations, id)
+	}
+
+	return plan.stats(), nil
+}
+
+// syncDocuments brings the stored documents up to date and fetches the
+// referenced documents that aren't stored yet. Only documents that were added
+// or changed are fetched, each from the endpoint of its type. Referenced
+// documents that no endpoint knows are returned as missing.
+func syncDocuments(client *Client, store *Store, referenced map[string]bool) (SyncStats, []string, error) {
+	// Planning only reads the store, the store is updated once all fetches are done
+	remotes := make([][]SyncVersion, len(documentTypes))
+	plans := make([]syncPlan, len(documentTypes))
+	fetched := make([][]Document, len(documentTypes))
+	err := forEachConcurrent(len(documentTypes), client.workers, func(i int) error {
+		remote, err := client.GetSyncVersions("documents/"+documentTypes[i], "")
+		if err != nil {
+			return fmt.Errorf("listing %s: %w", documentTypes[i], err)
+		}
+		remotes[i] = remote
+
+		// Only the documents we store or need are in scope
+		var relevant []SyncVersion
+		local := make(map[string]int64)
+		for _, rv := range remote {
+			if doc, ok := store.Documents[rv.ID]; ok {
+				local[rv.ID] = doc.Version
+				relevant = append(relevant, rv)
+			} else if referenced[rv.ID] {
+				relevant = append(relevant, rv)
+			}
+		}
+
+		plans[i] = planSync(relevant, local)
+		fetched[i], err = client.GetDocumentsBatch(plans[i].fetch, documentTypes[i])
+		if err != nil {
+			return fmt.Errorf("fetching %s: %w", documentTypes[i], err)
+		}
+		return nil
+	})
+	if err != nil {
+		return SyncStats{}, nil, err
+	}
+
+	var stats SyncStats
+	seen := make(map[string]bool)
+	for i := range documentTypes {
+		for _, rv := range remotes[i] {
+			seen[rv.ID] = true
+		}
+		for _, doc := range fetched[i] {
+			store.PutDocument(doc)
+		}
+
+		planStats := plans[i].stats()
+		stats.Added += planStats.Added
+		stats.Updated += planStats.Updated
+		stats.Unchanged += planStats.Unchanged
+	}
+
+	for id := range store.Documents {
+		if !seen[id] {
+			delete(store.Documents, id)
+			stats.Removed++
+		}
+	}
+
+	var missing []string
+	for id := range referenced {
+		if !seen[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+
+	return stats, missing, nil
+}
+
+// syncSalesInvoices brings the stored sales invoices up to date and fetches
+// the referenced invoices that aren't stored yet, like syncDocuments does for
+// documents. Referenced invoices Moneybird doesn't know are returned as missing.
+func syncSalesInvoices(client *Client, store *Store, referenced map[string]bool) (SyncStats, []string, error) {
+	remote, err := client.GetSyncVersions("sales_invoices", "")
+	if err != nil {
+		return SyncStats{}, nil, fmt.Errorf("listing sales invoices: %w", err)
+	}
+
+	var relevant []SyncVersion
+	local := make(map[string]int64)
+	seen := make(map[string]bool)
+	for _, rv := range remote {
+		seen[rv.ID] = true
+		if invoice, ok := store.SalesInvoices[rv.ID]; ok {
+			local[rv.ID] = invoice.Version
+			relevant = append(relevant, rv)
+		} else if referenced[rv.ID] {
+			relevant = append(relevant, rv)
+		}
+	}
+
+	plan := planSync(relevant, local)
+	fetched, err := client.GetSalesInvoicesBatch(plan.fetch)
+	if err != nil {
+		return SyncStats{}, nil, fmt.Errorf("fetching sales invoices: %w", err)
+	}
+	for _, invoice := range fetched {
+		store.PutSalesInvoice(invoice)
+	}
+
+	stats := plan.stats()
+	for id := range store.SalesInvoices {
+		if !seen[id] {
+			delete(store.SalesInvoices, id)
+			stats.Removed++
+		}
+	}
+
+	var missing []string
+	for id := range referenced {
+		if !seen[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+
+	return stats, missing, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSyncAPI serves the synchronization endpoints of a few resources from
+// records in memory, and remembers which IDs were fetched
+type fakeSyncAPI struct {
+	mu      sync.Mutex
+	records map[string][]map[string]interface{} // resource → records with an "id" and "version"
+	fetched map[string][]string                 // resource → IDs fetched in full
+}
+
+func newFakeSyncAPI(t *testing.T, records map[string][]map[string]interface{}) (*fakeSyncAPI, *Client) {
+	api := &fakeSyncAPI{records: records, fetched: make(map[string][]string)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resource := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/synchronization.json")
+		api.mu.Lock()
+		defer api.mu.Unlock()
+
+		if r.Method == "GET" {
+			versions := []SyncVersion{}
+			for _, record := range api.records[resource] {
+				versions = append(versions, SyncVersion{ID: record["id"].(string), Version: int64(record["version"].(int))})
+			}
+			json.NewEncoder(w).Encode(versions)
+			return
+		}
+
+		var request struct {
+			IDs []string `json:"ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		api.fetched[resource] = append(api.fetched[resource], request.IDs...)
+		wanted := make(map[string]bool)
+		for _, id := range request.IDs {
+			wanted[id] = true
+		}
+		found := []map[string]interface{}{}
+		for _, record := range api.records[resource] {
+			if wanted[record["id"].(string)] {
+				found = append(found, record)
+			}
+		}
+		json.NewEncoder(w).Encode(found)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("token")
+	client.apiURL = server.URL
+	return api, client
+}
+
+// fetchedIDs returns the IDs fetched from a resource, sorted
+func (api *fakeSyncAPI) fetchedIDs(resource string) []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	ids := append([]string{}, api.fetched[resource]...)
+	sort.Strings(ids)
+	return ids
+}
+
+func TestPlanSync(t *testing.T) {
+	remote := []SyncVersion{{ID: "new", Version: 1}, {ID: "changed", Version: 3}, {ID: "same", Version: 2}}
+	local := map[string]int64{"changed": 2, "same": 2, "gone": 5}
+
+	plan := planSync(remote, local)
+	if !reflect.DeepEqual(plan.fetch, []string{"changed", "new"}) || !reflect.DeepEqual(plan.removed, []string{"gone"}) {
+		t.Errorf("plan fetches %v and removes %v, want changed and new fetched and gone removed", plan.fetch, plan.removed)
+	}
+	if stats := plan.stats(); stats != (SyncStats{Added: 1, Updated: 1, Unchanged: 1, Removed: 1}) {
+		t.Errorf("stats = %v", stats)
+	}
+}
+
+func TestSyncFinancialMutationsFetchesOnlyChanges(t *testing.T) {
+	api, client := newFakeSyncAPI(t, map[string][]map[string]interface{}{
+		"financial_mutations": {
+			{"id": "same", "version": 1, "date": "2026-03-02", "amount": "-10.0"},
+			{"id": "changed", "version": 2, "date": "2026-03-03", "amount": "-25.0"},
+			{"id": "new", "version": 1, "date": "2026-03-04", "amount": "-5.0"},
+		},
+	})
+
+	store, err := OpenStore(t.TempDir() + "/store.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.PutFinancialMutation(FinancialMutation{ID: "same", Version: 1, Date: "2026-03-02", Amount: "-10.0"})
+	store.PutFinancialMutation(FinancialMutation{ID: "changed", Version: 1, Date: "2026-03-03", Amount: "-20.0"})
+	store.PutFinancialMutation(FinancialMutation{ID: "deleted", Version: 1, Date: "2026-03-05", Amount: "-1.0"})
+	store.PutFinancialMutation(FinancialMutation{ID: "february", Version: 1, Date: "2026-02-27", Amount: "-3.0"})
+
+	stats, err := syncFinancialMutations(client, store, "2026-03-01", "2026-03-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (SyncStats{Added: 1, Updated: 1, Unchanged: 1, Removed: 1}) {
+		t.Errorf("stats = %v", stats)
+	}
+	if fetched := api.fetchedIDs("financial_mutations"); !reflect.DeepEqual(fetched, []string{"changed", "new"}) {
+		t.Errorf("fetched %v, want only the changed and new mutations", fetched)
+	}
+	if store.FinancialMutations["changed"].Amount != "-25.0" {
+		t.Errorf("changed mutation = %+v, want the new amount", store.FinancialMutations["changed"])
+	}
+	if _, ok := store.FinancialMutations["deleted"]; ok {
+		t.Error("mutation deleted in Moneybird is still stored")
+	}
+	if _, ok := store.FinancialMutations["february"]; !ok {
+		t.Error("mutation outside the period was removed")
+	}
+}
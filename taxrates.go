@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultVATRate is used for revenue when the administration has no active
+// sales tax rate, e.g. in snapshots written before tax rates were stored
+const defaultVATRate = 0.21
+
+// TaxRate represents a Moneybird tax rate
+type TaxRate struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Percentage  string    `json:"percentage"`
+	TaxRateType string    `json:"tax_rate_type"` // sales_invoice, purchase_invoice or general_journal_document
+	Country     string    `json:"country"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// rate returns the percentage as a fraction, e.g. 0.21
+func (r TaxRate) rate() float64 {
+	var percentage float64
+	fmt.Sscanf(r.Percentage, "%f", &percentage)
+	return percentage / 100
+}
+
+// GetTaxRates fetches the administration's tax rates
+func (c *Client) GetTaxRates() ([]TaxRate, error) {
+	body, err := c.doRequest("GET", "tax_rates.json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []TaxRate
+	if err := json.Unmarshal(body, &rates); err != nil {
+		return nil, fmt.Errorf("unmarshaling response: %w", err)
+	}
+
+	return rates, nil
+}
+
+// salesVATRate returns the standard VAT rate for revenue: the highest active
+// sales rate, or defaultVATRate when there is none
+func salesVATRate(rates []TaxRate) float64 {
+	best := -1.0
+	for _, r := range rates {
+		if r.Active && r.TaxRateType == "sales_invoice" && r.rate() > best {
+			best = r.rate()
+		}
+	}
+	if best < 0 {
+		return defaultVATRate
+	}
+	return best
+}
+
+// VATPart is the net and VAT amount of a document detail or invoice
+type VATPart struct {
+	TaxRateID string
+	Net       float64
+	VAT       float64
+}
+
+// splitVAT splits an amount into its net and VAT parts using the tax rate.
+// inclTax tells whether the amount already includes VAT. Unknown tax rates
+// are treated as 0%.
+func splitVAT(amount float64, taxRateID string, inclTax bool, rates map[string]TaxRate) VATPart {
+	rate := rates[taxRateID].rate()
+	part := VATPart{TaxRateID: taxRateID, Net: amount}
+	if inclTax {
+		part.Net = amount / (1 + rate)
+	}
+	part.VAT = part.Net * rate
+	return part
+}
+
+// DocumentLine is the total of a document detail split into net and VAT.
+// Together the lines of a document add up to its total including VAT.
+type DocumentLine struct {
+	Detail DocumentDetail
+	Net    float64
+	VAT    float64
+}
+
+// Total is the line total including VAT
+func (l DocumentLine) Total() float64 {
+	return l.Net + l.VAT
+}
+
+// quantity parses the detail's amount, e.g. "2" or "2 x". Details without
+// one, such as general journal entries, count once.
+func (d DocumentDetail) quantity() float64 {
+	amount := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(d.Amount), "x"))
+	var quantity float64
+	if _, err := fmt.Sscanf(strings.ReplaceAll(amount, ",", "."), "%f", &quantity); err != nil {
+		return 1
+	}
+	return quantity
+}
+
+// documentLines works out the total of every line of a document. The
+// discounted total excluding tax is the most exact net amount; without it the
+// unit price times the quantity is used, including or excluding VAT as the
+// document's prices are.
+func documentLines(doc Document, rates map[string]TaxRate) []DocumentLine {
+	var lines []DocumentLine
+	for _, detail := range doc.lines() {
+		var part VATPart
+		if detail.TotalPriceExclTaxWithDiscount != "" {
+			var net float64
+			fmt.Sscanf(detail.TotalPriceExclTaxWithDiscount, "%f", &net)
+			part = splitVAT(net, detail.TaxRateID, false, rates)
+		} else {
+			var price float64
+			fmt.Sscanf(detail.Price, "%f", &price)
+			part = splitVAT(price*detail.quantity(), detail.TaxRateID, doc.PricesAreInclTax, rates)
+		}
+		lines = append(lines, DocumentLine{Detail: detail, Net: part.Net, VAT: part.VAT})
+	}
+	return lines
+}
+
+// documentLineAmounts works out what a payment of a document or sales invoice
+// counts for on each of its lines: the payment's share of it, converted to
+// euros, in the booking sign. Payment prices are not negative like booking
+// prices, so a purchase comes out negative and a sale positive.
+func documentLineAmounts(snap *Snapshot, payment Payment, doc Document) []DocumentLine {
+	_, factor, _ := snap.documentPayment(payment, doc)
+	share := -doc.paidShare(payment.Price) * factor
+	if payment.InvoiceType == "SalesInvoice" {
+		share = -share
+	}
+	lines := documentLines(doc, taxRateMap(snap.TaxRates))
+	for i := range lines {
+		lines[i].Net *= share
+		lines[i].VAT *= share
+	}
+	return lines
+}
+
+// taxRateMap indexes tax rates by ID
+func taxRateMap(rates []TaxRate) map[string]TaxRate {
+	byID := make(map[string]TaxRate)
+	for _, r := range rates {
+		byID[r.ID] = r
+	}
+	return byID
+}
+
+// VATTotal is the net and VAT paid at a single tax rate
+type VATTotal struct {
+	TaxRate TaxRate
+	Net     float64
+	VAT     float64
+}
+
+// aggregateVAT totals the net and VAT parts of the documents paid in the
+// snapshot per tax rate. Partial payments count their share of the document.
+func aggregateVAT(snap *Snapshot) []VATTotal {
+	rates := taxRateMap(snap.TaxRates)
+
+	byRate := make(map[string]*VATTotal)
+	for _, mut := range snap.Mutations {
+		for _, payment := range mut.Payments {
+			doc, ok := snap.Documents[payment.InvoiceID]
+			if payment.InvoiceType != "Document" || !ok {
+				continue
+			}
+
+			for _, line := range documentLineAmounts(snap, payment, doc) {
+				taxRateID := line.Detail.TaxRateID
+				total, ok := byRate[taxRateID]
+				if !ok {
+					rate, known := rates[taxRateID]
+					if !known {
+						rate = TaxRate{ID: taxRateID, Name: "Unknown tax rate", Percentage: "0"}
+					}
+					total = &VATTotal{TaxRate: rate}
+					byRate[taxRateID] = total
+				}
+				// Purchases are negative, the VAT on them is reclaimable
+				total.Net -= line.Net
+				total.VAT -= line.VAT
+			}
+		}
+	}
+
+	var totals []VATTotal
+	for _, total := range byRate {
+		if math.Abs(total.Net) >= 0.005 || math.Abs(total.VAT) >= 0.005 {
+			totals = append(totals, *total)
+		}
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].TaxRate.rate() != totals[j].TaxRate.rate() {
+			return totals[i].TaxRate.rate() > totals[j].TaxRate.rate()
+		}
+		return totals[i].TaxRate.Name < totals[j].TaxRate.Name
+	})
+	return totals
+}
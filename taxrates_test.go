@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDocumentLinesUseLineTotals(t *testing.T) {
+	rates := taxRateMap([]TaxRate{{ID: "high", Percentage: "21"}, {ID: "low", Percentage: "9"}})
+	doc := Document{
+		PricesAreInclTax: false,
+		Details: []DocumentDetail{
+			// 3 × €10 excl. VAT
+			{LedgerAccountID: "office", TaxRateID: "high", Amount: "3 x", Price: "10.00"},
+			// 2 × €5 with a discount, the discounted total wins
+			{LedgerAccountID: "food", TaxRateID: "low", Amount: "2", Price: "5.00", TotalPriceExclTaxWithDiscount: "9.00"},
+		},
+	}
+
+	lines := documentLines(doc, rates)
+	want := []struct{ net, vat float64 }{{30, 6.3}, {9, 0.81}}
+	for i, line := range lines {
+		if math.Abs(line.Net-want[i].net) > 1e-9 || math.Abs(line.VAT-want[i].vat) > 1e-9 {
+			t.Errorf("line %d = %.2f + %.2f VAT, want %.2f + %.2f", i, line.Net, line.VAT, want[i].net, want[i].vat)
+		}
+	}
+
+	// With prices including VAT the quantity still counts
+	doc.PricesAreInclTax = true
+	doc.Details[1].TotalPriceExclTaxWithDiscount = ""
+	lines = documentLines(doc, rates)
+	if got := lines[0].Total(); math.Abs(got-30) > 1e-9 {
+		t.Errorf("incl. VAT line total = %.2f, want 30.00", got)
+	}
+	if got := lines[1].Total(); math.Abs(got-10) > 1e-9 {
+		t.Errorf("incl. VAT line total = %.2f, want 10.00", got)
+	}
+}
+
+func TestDocumentLineAmountsAgree(t *testing.T) {
+	project := "kitchen"
+	snap := &Snapshot{
+		LedgerAccounts: []LedgerAccount{{ID: "office", AccountType: "expenses"}},
+		TaxRates:       []TaxRate{{ID: "high", Percentage: "21"}},
+		Documents: map[string]Document{
+			"doc": {
+				ID:                "doc",
+				TotalPriceInclTax: "36.30",
+				Details: []DocumentDetail{
+					{LedgerAccountID: "office", TaxRateID: "high", Amount: "3", Price: "10.00", ProjectID: &project},
+				},
+			},
+		},
+		Mutations: []FinancialMutation{{
+			ID:       "m1",
+			Amount:   "-18.15",
+			Payments: []Payment{{InvoiceType: "Document", InvoiceID: "doc", Price: "18.15"}},
+		}},
+	}
+
+	totals, _, _ := aggregateTotals(snap, nil)
+	if got := totals["office"]; math.Abs(got+18.15) > 0.005 {
+		t.Errorf("office total = %.2f, want -18.15 (half of the document)", got)
+	}
+
+	vat := aggregateVAT(snap)
+	if len(vat) != 1 || math.Abs(vat[0].Net-15) > 0.005 || math.Abs(vat[0].VAT-3.15) > 0.005 {
+		t.Errorf("VAT = %+v, want €15.00 net and €3.15 VAT", vat)
+	}
+
+	reports := projectReports(snap, nil, nil)
+	if len(reports) != 1 || math.Abs(reports[0].Spend-18.15) > 0.005 {
+		t.Errorf("project reports = %+v, want €18.15 spent on the project", reports)
+	}
+}
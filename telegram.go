@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	telegramAPIURL = "https://api.telegram.org"
+
+	// Limits from the Bot API, counted in characters after entity parsing.
+	// We count the raw HTML, which is never shorter.
+	maxCaptionLength  = 1024
+	maxMessageLength  = 4096
+	maxMediaGroupSize = 10
+)
+
+// TelegramBot is a minimal client for the Telegram Bot API
+type TelegramBot struct {
+	apiURL string
+	token  string
+	chatID string
+	client *http.Client
+}
+
+// NewTelegramBot creates a new Telegram bot client for the given chat
+func NewTelegramBot(token, chatID string) *TelegramBot {
+	return &TelegramBot{
+		apiURL: telegramAPIURL,
+		token:  token,
+		chatID: chatID,
+		// Long polling keeps getUpdates open for up to a minute
+		client: &http.Client{Timeout: 70 * time.Second},
+	}
+}
+
+// InlineKeyboardButton is a single button below a Telegram message
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// InlineKeyboardMarkup holds the rows of buttons attached to a message
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// TelegramChat identifies the chat a message belongs to
+type TelegramChat struct {
+	ID int64 `json:"id"`
+}
+
+// TelegramMessage is the subset of a Telegram message we use
+type TelegramMessage struct {
+	MessageID int64        `json:"message_id"`
+	Chat      TelegramChat `json:"chat"`
+	Text      string       `json:"text"`
+}
+
+// CallbackQuery is sent when a user taps an inline keyboard button
+type CallbackQuery struct {
+	ID      string           `json:"id"`
+	Data    string           `json:"data"`
+	Message *TelegramMessage `json:"message"`
+}
+
+// TelegramUpdate is a single entry returned by getUpdates
+type TelegramUpdate struct {
+	UpdateID      int64            `json:"update_id"`
+	Message       *TelegramMessage `json:"message"`
+	CallbackQuery *CallbackQuery   `json:"callback_query"`
+}
+
+// call invokes a Bot API method with a JSON payload and decodes the result
+func (b *TelegramBot) call(method string, payload interface{}, result interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/bot%s/%s", b.apiURL, b.token, method)
+	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("unmarshaling response: %w", err)
+	}
+	if err := json.Unmarshal(envelope.Result, result); err != nil {
+		return fmt.Errorf("unmarshaling result: %w", err)
+	}
+
+	return nil
+}
+
+// SendMessage sends an HTML formatted text message, optionally with an inline keyboard
+func (b *TelegramBot) SendMessage(text string, keyboard *InlineKeyboardMarkup) (*TelegramMessage, error) {
+	payload := map[string]interface{}{
+		"chat_id":    b.chatID,
+		"text":       text,
+		"parse_mode": "HTML",
+	}
+	if keyboard != nil {
+		payload["reply_markup"] = keyboard
+	}
+
+	var msg TelegramMessage
+	if err := b.call("sendMessage", payload, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// EditMessageText replaces the text of an earlier message and removes its keyboard
+func (b *TelegramBot) EditMessageText(messageID int64, text string) error {
+	payload := map[string]interface{}{
+		"chat_id":      b.chatID,
+		"message_id":   messageID,
+		"text":         text,
+		"parse_mode":   "HTML",
+		"reply_markup": InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{}},
+	}
+	return b.call("editMessageText", payload, nil)
+}
+
+// AnswerCallbackQuery acknowledges a button tap, showing a short toast in the client
+func (b *TelegramBot) AnswerCallbackQuery(callbackID, text string) error {
+	payload := map[string]interface{}{
+		"callback_query_id": callbackID,
+		"text":              text,
+	}
+	return b.call("answerCallbackQuery", payload, nil)
+}
+
+// GetUpdates long-polls for new updates starting at offset
+func (b *TelegramBot) GetUpdates(offset int64) ([]TelegramUpdate, error) {
+	payload := map[string]interface{}{
+		"offset":          offset,
+		"timeout":         60,
+		"allowed_updates": []string{"message", "callback_query"},
+	}
+
+	var updates []TelegramUpdate
+	if err := b.call("getUpdates", payload, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// postMultipart invokes a Bot API method with form fields and file uploads.
+// Files are keyed by their form field name.
+func (b *TelegramBot) postMultipart(method string, fields map[string]string, files map[string]string) error {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for key, value := range fields {
+		_ = writer.WriteField(key, value)
+	}
+
+	for field, path := range files {
+		if err := attachFile(writer, field, path); err != nil {
+			return err
+		}
+	}
+
+	writer.Close()
+
+	url := fmt.Sprintf("%s/bot%s/%s", b.apiURL, b.token, method)
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("telegram API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// SendPhoto sends an image with an HTML formatted caption
+func (b *TelegramBot) SendPhoto(caption, imagePath string) error {
+	fields := map[string]string{
+		"chat_id":    b.chatID,
+		"caption":    caption,
+		"parse_mode": "HTML",
+	}
+	return b.postMultipart("sendPhoto", fields, map[string]string{"photo": imagePath})
+}
+
+// SendMediaGroup sends up to 10 images as a single album. The caption is
+// shown below the album, attached to the first image.
+func (b *TelegramBot) SendMediaGroup(caption string, imagePaths []string) error {
+	if len(imagePaths) > maxMediaGroupSize {
+		return fmt.Errorf("media group can hold at most %d images, got %d", maxMediaGroupSize, len(imagePaths))
+	}
+
+	type inputMediaPhoto struct {
+		Type      string `json:"type"`
+		Media     string `json:"media"`
+		Caption   string `json:"caption,omitempty"`
+		ParseMode string `json:"parse_mode,omitempty"`
+	}
+
+	var media []inputMediaPhoto
+	files := make(map[string]string)
+	for i, path := range imagePaths {
+		name := fmt.Sprintf("photo%d", i)
+		item := inputMediaPhoto{Type: "photo", Media: "attach://" + name}
+		if i == 0 && caption != "" {
+			item.Caption = caption
+			item.ParseMode = "HTML"
+		}
+		media = append(media, item)
+		files[name] = path
+	}
+
+	mediaJSON, err := json.Marshal(media)
+	if err != nil {
+		return fmt.Errorf("marshaling media: %w", err)
+	}
+
+	fields := map[string]string{
+		"chat_id": b.chatID,
+		"media":   string(mediaJSON),
+	}
+	return b.postMultipart("sendMediaGroup", fields, files)
+}
+
+// splitMessage cuts HTML text into a head of at most limit characters and the
+// rest, breaking on line boundaries so no tag is split. Our messages never
+// open a tag on one line and close it on another. Discord's markdown splits the
+// same way.
+func splitMessage(text string, limit int) (string, string) {
+	if utf8.RuneCountInString(text) <= limit {
+		return text, ""
+	}
+
+	count := 0
+	end := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		n := utf8.RuneCountInString(line)
+		if count+n > limit {
+			break
+		}
+		count += n
+		end += len(line)
+	}
+
+	if end == 0 {
+		return cutLine(text, limit)
+	}
+	return text[:end], text[end:]
+}
+
+// cutLine cuts a single line of HTML longer than the limit. It only cuts
+// outside tags and entities, and closes the tags still open at the cut in the
+// head and reopens them in the rest, so both parse on their own.
+func cutLine(text string, limit int) (string, string) {
+	type openTag struct{ name, tag string }
+	var open []openTag
+	closing := func(tags []openTag) string {
+		var sb strings.Builder
+		for i := len(tags) - 1; i >= 0; i-- {
+			sb.WriteString("</" + tags[i].name + ">")
+		}
+		return sb.String()
+	}
+
+	cut, count := 0, 0
+	var openAtCut []openTag
+	tagStart, inEntity := -1, false
+	for i, r := range text {
+		if tagStart < 0 && !inEntity && count+utf8.RuneCountInString(closing(open)) <= limit {
+			cut = i
+			openAtCut = append(openAtCut[:0], open...)
+		}
+		if count >= limit {
+			break
+		}
+		count++
+
+		switch {
+		case tagStart >= 0:
+			if r == '>' {
+				tag := text[tagStart : i+1]
+				name := strings.Fields(strings.Trim(tag, "</>"))
+				switch {
+				case len(name) == 0:
+				case strings.HasPrefix(tag, "</"):
+					for j := len(open) - 1; j >= 0; j-- {
+						if open[j].name == name[0] {
+							open = open[:j]
+							break
+						}
+					}
+				default:
+					open = append(open, openTag{name: name[0], tag: tag})
+				}
+				tagStart = -1
+			}
+		case inEntity:
+			inEntity = r != ';'
+		case r == '<':
+			tagStart = i
+		case r == '&':
+			inEntity = true
+		}
+	}
+	if cut == 0 {
+		// Not a single safe cut within the limit, e.g. a huge tag
+		runes := []rune(text)
+		return string(runes[:limit]), string(runes[limit:])
+	}
+
+	var reopen strings.Builder
+	for _, tag := range openAtCut {
+		reopen.WriteString(tag.tag)
+	}
+	return text[:cut] + closing(openAtCut), reopen.String() + text[cut:]
+}
+
+// sendLongMessage sends text as one or more messages within Telegram's length limit
+func (b *TelegramBot) sendLongMessage(text string) error {
+	for strings.TrimSpace(text) != "" {
+		var chunk string
+		chunk, text = splitMessage(text, maxMessageLength)
+		if _, err := b.SendMessage(chunk, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TelegramNotifier sends the report as a photo with an HTML caption
+type TelegramNotifier struct {
+	bot *TelegramBot
+}
+
+// Name returns the channel name
+func (n *TelegramNotifier) Name() string {
+	return "Telegram"
+}
+
+// Notify sends the charts as a photo or album with the report as caption.
+// Whatever doesn't fit in the caption follows as text messages.
+func (n *TelegramNotifier) Notify(report Report) error {
+	message := formatReportText(report, htmlStyle)
+
+	charts := report.Charts
+	first := true
+	for len(charts) > 0 {
+		batch := charts
+		if len(batch) > maxMediaGroupSize {
+			batch = batch[:maxMediaGroupSize]
+		}
+		charts = charts[len(batch):]
+
+		// Only the first photo or album carries a caption
+		var caption string
+		if first {
+			caption, message = splitMessage(message, maxCaptionLength)
+			first = false
+		}
+
+		var err error
+		if len(batch) == 1 {
+			err = n.bot.SendPhoto(caption, batch[0])
+		} else {
+			err = n.bot.SendMediaGroup(caption, batch)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return n.bot.sendLongMessage(message)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// telegramRequest is a Bot API call as seen by the stand-in server
+type telegramRequest struct {
+	method string
+	fields map[string]string
+	files  []string
+}
+
+// newTelegramServer starts a stand-in Bot API that records the calls for the
+// given token and answers them with status
+func newTelegramServer(t *testing.T, token string, status int) (*httptest.Server, *[]telegramRequest) {
+	t.Helper()
+	var requests []telegramRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The Bot API authenticates with the token in the path
+		prefix := "/bot" + token + "/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			t.Errorf("path %q doesn't carry the bot token", r.URL.Path)
+			http.Error(w, `{"ok": false, "description": "Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		req := telegramRequest{method: strings.TrimPrefix(r.URL.Path, prefix), fields: make(map[string]string)}
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("parsing multipart form: %v", err)
+			}
+			for key, values := range r.MultipartForm.Value {
+				req.fields[key] = values[0]
+			}
+			for field := range r.MultipartForm.File {
+				req.files = append(req.files, field)
+			}
+		} else {
+			var payload map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&payload)
+			for key, value := range payload {
+				if s, ok := value.(string); ok {
+					req.fields[key] = s
+				}
+			}
+		}
+		requests = append(requests, req)
+
+		if status != http.StatusOK {
+			http.Error(w, `{"ok": false, "description": "Bad Request: chat not found"}`, status)
+			return
+		}
+		w.Write([]byte(`{"ok": true, "result": {"message_id": 1, "chat": {"id": 42}}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func testTelegramNotifier(serverURL string) *TelegramNotifier {
+	bot := NewTelegramBot("123:secret", "42")
+	bot.apiURL = serverURL
+	return &TelegramNotifier{bot: bot}
+}
+
+func TestTelegramNotifierPhoto(t *testing.T) {
+	server, requests := newTelegramServer(t, "123:secret", http.StatusOK)
+	chart := writeTestChart(t, "budget_chart.png")
+
+	if err := testTelegramNotifier(server.URL).Notify(testReport(chart)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("made %d calls, want one sendPhoto", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.method != "sendPhoto" || req.fields["chat_id"] != "42" || req.fields["parse_mode"] != "HTML" {
+		t.Errorf("unexpected call %+v", req)
+	}
+	if len(req.files) != 1 || req.files[0] != "photo" {
+		t.Errorf("files = %v, want the photo", req.files)
+	}
+	if caption := req.fields["caption"]; !strings.Contains(caption, "<b>Financial Report 2024-01</b>") ||
+		!strings.Contains(caption, "Food &amp; Drinks") {
+		t.Errorf("caption %q", caption)
+	}
+}
+
+func TestTelegramNotifierAlbumAndLongText(t *testing.T) {
+	server, requests := newTelegramServer(t, "123:secret", http.StatusOK)
+	charts := []string{writeTestChart(t, "a.png"), writeTestChart(t, "b.png")}
+
+	// Enough categories to overflow the caption
+	report := testReport(charts...)
+	for i := 0; i < 60; i++ {
+		report.Categories = append(report.Categories, ReportCategory{Name: strings.Repeat("x", 20), Amount: float64(i)})
+	}
+	if err := testTelegramNotifier(server.URL).Notify(report); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if len(*requests) < 2 || (*requests)[0].method != "sendMediaGroup" || (*requests)[1].method != "sendMessage" {
+		t.Fatalf("calls = %+v, want an album followed by text", *requests)
+	}
+	var media []map[string]string
+	json.Unmarshal([]byte((*requests)[0].fields["media"]), &media)
+	if len(media) != 2 || len([]rune(media[0]["caption"])) > maxCaptionLength || media[1]["caption"] != "" {
+		t.Errorf("media = %v, want a caption on the first photo only", media)
+	}
+	if text := (*requests)[1].fields["text"]; text == "" || (*requests)[1].fields["parse_mode"] != "HTML" {
+		t.Errorf("follow-up message %+v", (*requests)[1])
+	}
+}
+
+func TestTelegramNotifierTextOnly(t *testing.T) {
+	server, requests := newTelegramServer(t, "123:secret", http.StatusOK)
+
+	if err := testTelegramNotifier(server.URL).Notify(testReport()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(*requests) != 1 || (*requests)[0].method != "sendMessage" || (*requests)[0].fields["chat_id"] != "42" {
+		t.Fatalf("calls = %+v, want one sendMessage", *requests)
+	}
+}
+
+func TestTelegramNotifierError(t *testing.T) {
+	server, _ := newTelegramServer(t, "123:secret", http.StatusBadRequest)
+	chart := writeTestChart(t, "chart.png")
+
+	for _, report := range []Report{testReport(), testReport(chart)} {
+		err := testTelegramNotifier(server.URL).Notify(report)
+		if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "chat not found") {
+			t.Errorf("Notify with %d charts error = %v, want the status and description", len(report.Charts), err)
+		}
+	}
+}
+
+func TestSplitMessageOnLines(t *testing.T) {
+	head, rest := splitMessage("one\ntwo\nthree\n", 9)
+	if head != "one\ntwo\n" || rest != "three\n" {
+		t.Errorf("split = %q, %q", head, rest)
+	}
+}
+
+func TestSplitMessageLongLine(t *testing.T) {
+	tests := []struct {
+		text, head, rest string
+		limit            int
+	}{
+		// Never inside an entity
+		{"Tom &amp; Jerry", "Tom ", "&amp; Jerry", 7},
+		// Never inside a tag
+		{"ab<b>cd</b>", "ab", "<b>cd</b>", 4},
+		// Tags open at the cut are closed and reopened
+		{"<b>abcdefghij</b>", "<b>abcdef</b>", "<b>ghij</b>", 13},
+		{`<a href="x">ab <b>cd &lt; ef</b></a>`, `<a href="x">ab <b>cd </b></a>`, `<a href="x"><b>&lt; ef</b></a>`, 30},
+	}
+	for _, tt := range tests {
+		head, rest := splitMessage(tt.text, tt.limit)
+		if head != tt.head || rest != tt.rest {
+			t.Errorf("splitMessage(%q, %d) = %q, %q, want %q, %q", tt.text, tt.limit, head, rest, tt.head, tt.rest)
+		}
+		if n := len([]rune(head)); n > tt.limit {
+			t.Errorf("splitMessage(%q, %d): head has %d characters", tt.text, tt.limit, n)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// transferWindowDays is how far apart the two sides of a transfer may be booked
+const transferWindowDays = 3
+
+// TransferConfig lists our own accounts. OWN_IBANS and SAVINGS_IBANS are
+// comma-separated; savings accounts count as own accounts too.
+type TransferConfig struct {
+	OwnIBANs     map[string]bool
+	SavingsIBANs map[string]bool
+}
+
+// Transfer is money moved between our own accounts. In is nil when the other
+// account isn't in Moneybird and the transfer was recognized by its IBAN.
+type Transfer struct {
+	Out    *FinancialMutation
+	In     *FinancialMutation
+	Amount float64
+	Saved  float64 // positive when moved into savings, negative when taken out
+}
+
+// transferConfigFromEnv reads the own and savings IBANs from the environment
+func transferConfigFromEnv() TransferConfig {
+	config := TransferConfig{OwnIBANs: make(map[string]bool), SavingsIBANs: make(map[string]bool)}
+	for _, iban := range splitList(os.Getenv("SAVINGS_IBANS")) {
+		config.SavingsIBANs[normalizeIBAN(iban)] = true
+		config.OwnIBANs[normalizeIBAN(iban)] = true
+	}
+	for _, iban := range splitList(os.Getenv("OWN_IBANS")) {
+		config.OwnIBANs[normalizeIBAN(iban)] = true
+	}
+	return config
+}
+
+// detectTransfers finds internal transfers: an outgoing and incoming mutation
+// of the same amount on two different financial accounts within a few days,
+// between the same account holder or from/to a known own IBAN, and mutations
+// whose counterparty is one of our own IBANs
+func detectTransfers(mutations []FinancialMutation, config TransferConfig) []Transfer {
+	var transfers []Transfer
+	matched := make(map[string]bool)
+
+	isOwn := func(mut FinancialMutation) bool {
+		return config.OwnIBANs[normalizeIBAN(mut.ContraAccountNumber)]
+	}
+
+	// Incoming mutations by amount in cents, so each outgoing one only looks
+	// at candidates of the same amount
+	incoming := make(map[int64][]int)
+	for i, mut := range mutations {
+		var amount float64
+		fmt.Sscanf(mut.Amount, "%f", &amount)
+		if amount > 0 {
+			cents := int64(math.Round(amount * 100))
+			incoming[cents] = append(incoming[cents], i)
+		}
+	}
+
+	for i := range mutations {
+		out := &mutations[i]
+		amount, isOut := outgoing(*out)
+		if !isOut || matched[out.ID] {
+			continue
+		}
+		outDate, err := time.Parse("2006-01-02", out.Date)
+		if err != nil {
+			continue
+		}
+
+		for _, j := range incoming[int64(math.Round(amount*100))] {
+			in := &mutations[j]
+			if matched[in.ID] || in.FinancialAccountID == "" || in.FinancialAccountID == out.FinancialAccountID {
+				continue
+			}
+			inDate, err := time.Parse("2006-01-02", in.Date)
+			if err != nil || math.Abs(inDate.Sub(outDate).Hours()) > transferWindowDays*24 {
+				continue
+			}
+			sameHolder := normalizeMerchantName(out.ContraAccountName) != "" &&
+				normalizeMerchantName(out.ContraAccountName) == normalizeMerchantName(in.ContraAccountName)
+			if !sameHolder && !isOwn(*out) && !isOwn(*in) {
+				continue
+			}
+
+			matched[out.ID], matched[in.ID] = true, true
+			transfer := Transfer{Out: out, In: in, Amount: amount}
+			if config.SavingsIBANs[normalizeIBAN(out.ContraAccountNumber)] {
+				transfer.Saved = amount
+			} else if config.SavingsIBANs[normalizeIBAN(in.ContraAccountNumber)] {
+				transfer.Saved = -amount
+			}
+			transfers = append(transfers, transfer)
+			break
+		}
+	}
+
+	// One-sided transfers to or from own accounts that aren't in Moneybird
+	for i := range mutations {
+		mut := &mutations[i]
+		if matched[mut.ID] || !isOwn(*mut) {
+			continue
+		}
+		matched[mut.ID] = true
+
+		amount, isOut := outgoing(*mut)
+		savings := config.SavingsIBANs[normalizeIBAN(mut.ContraAccountNumber)]
+		transfer := Transfer{Amount: math.Abs(amount)}
+		if isOut {
+			transfer.Out = mut
+			if savings {
+				transfer.Saved = amount
+			}
+		} else {
+			transfer.In = mut
+			if savings {
+				transfer.Saved = amount // amount is negative for incoming money
+			}
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers
+}
+
+// transferIDs returns the IDs of all mutations that are part of a transfer
+func transferIDs(transfers []Transfer) map[string]bool {
+	ids := make(map[string]bool)
+	for _, t := range transfers {
+		if t.Out != nil {
+			ids[t.Out.ID] = true
+		}
+		if t.In != nil {
+			ids[t.In.ID] = true
+		}
+	}
+	return ids
+}
+
+// withoutTransfers drops the mutations that are part of an internal transfer
+func withoutTransfers(mutations []FinancialMutation, config TransferConfig) []FinancialMutation {
+	ids := transferIDs(detectTransfers(mutations, config))
+	var spending []FinancialMutation
+	for _, mut := range mutations {
+		if !ids[mut.ID] {
+			spending = append(spending, mut)
+		}
+	}
+	return spending
+}
+
+// spendingSnapshot returns a copy of the snapshot without internal transfers
+func spendingSnapshot(snap *Snapshot, config TransferConfig) *Snapshot {
+	spending := *snap
+	spending.Mutations = withoutTransfers(snap.Mutations, config)
+	return &spending
+}
+
+// savedAmount totals what the transfers moved into savings
+func savedAmount(transfers []Transfer) float64 {
+	var saved float64
+	for _, t := range transfers {
+		saved += t.Saved
+	}
+	return saved
+}
+
+// printTransfers lists the internal transfers
+func printTransfers(transfers []Transfer) {
+	if len(transfers) == 0 {
+		fmt.Println("   No internal transfers")
+		return
+	}
+	for _, t := range transfers {
+		marker := "↔"
+		if t.Saved > 0 {
+			marker = "🏦"
+		} else if t.Saved < 0 {
+			marker = "↩"
+		}
+		mut := t.Out
+		if mut == nil {
+			mut = t.In
+		}
+		fmt.Printf("   %s %s  €%9.2f  %s\n", marker, mut.Date, t.Amount, mut.ContraAccountName)
+	}
+	fmt.Printf("   Saved this month: €%.2f\n", savedAmount(transfers))
+}
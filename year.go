@@ -0,0 +1,570 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+// Sections of the yearly overview
+const (
+	sectionFamilyRoot = "Family Expenses (by root category)"
+	sectionFamilyLeaf = "Family Expenses (detailed)"
+	sectionRevenue    = "Revenue"
+	sectionBusiness   = "Business Expenses"
+	sectionBudget     = "Family Budget Calculation"
+)
+
+// MonthColumn is a month of the yearly overview: its budget figures and the
+// totals per category, in the report's signs
+type MonthColumn struct {
+	Figures  MonthFigures
+	VATRate  float64
+	Family   map[string]float64 // root category → total
+	Leaves   map[string]float64 // leaf category → total
+	Revenue  map[string]float64
+	Business map[string]float64
+	Empty    bool // no transactions in the month, its cells stay empty
+}
+
+// summarizeMonth aggregates a month's snapshot like the report does, without
+// internal transfers
+func summarizeMonth(snap *Snapshot, month string) MonthColumn {
+	snap = spendingSnapshot(snap, transferConfigFromEnv())
+	accountMap := make(map[string]LedgerAccount)
+	for _, acc := range snap.LedgerAccounts {
+		accountMap[acc.ID] = acc
+	}
+
+	column := MonthColumn{
+		Figures:  MonthFigures{Month: month},
+		VATRate:  salesVATRate(snap.TaxRates),
+		Family:   make(map[string]float64),
+		Leaves:   make(map[string]float64),
+		Revenue:  make(map[string]float64),
+		Business: make(map[string]float64),
+	}
+	totals, _, _ := aggregateTotals(snap, snap.LedgerAccounts)
+	for id, total := range totals {
+		acc, ok := accountMap[id]
+		if !ok {
+			continue
+		}
+		switch acc.AccountType {
+		case "revenue":
+			column.Revenue[acc.Name] += total
+			column.Figures.Revenue += total
+		case "expenses":
+			column.Business[acc.Name] += total
+			column.Figures.BusinessExpenses += total
+		case "equity":
+			column.Family[rootAccount(acc, accountMap).Name] += total
+			column.Leaves[acc.Name] += total
+			column.Figures.FamilySpending += total
+		}
+	}
+	_, _, column.Figures.FamilyBudget = familyBudgetFrom(column.Figures.Revenue, column.Figures.BusinessExpenses, column.VATRate)
+
+	if end, err := time.Parse("2006-01", month); err == nil {
+		column.Figures.Partial = snap.PeriodEnd < end.AddDate(0, 1, -1).Format("2006-01-02")
+	}
+	return column
+}
+
+// YearRow is a line of the yearly overview with a value per month
+type YearRow struct {
+	Section string
+	Name    string
+	Values  []float64
+	Percent bool // the values are fractions, e.g. the savings rate
+}
+
+// YearTable is the yearly overview: one column per month of the year, up to
+// the current month. Months without transactions have empty (NaN) cells.
+type YearTable struct {
+	Year    int
+	Months  []string // YYYY-MM
+	Rows    []YearRow
+	columns []MonthColumn
+}
+
+// Total sums a row, except for percentages: the savings rate of the year is
+// what was saved of the whole year's budget
+func (t *YearTable) Total(row YearRow) float64 {
+	if row.Percent {
+		var saved, budget float64
+		for _, column := range t.columns {
+			saved += column.Figures.Saved()
+			budget += column.Figures.FamilyBudget
+		}
+		if budget <= 0 {
+			return math.NaN()
+		}
+		return saved / budget
+	}
+	var total float64
+	for _, v := range row.Values {
+		if !math.IsNaN(v) {
+			total += v
+		}
+	}
+	return total
+}
+
+// MonthsWithData counts the months that have transactions
+func (t *YearTable) MonthsWithData() int {
+	var months int
+	for _, column := range t.columns {
+		if !column.Empty {
+			months++
+		}
+	}
+	return months
+}
+
+// Average is the row's total divided by the months with transactions, the
+// same for every row
+func (t *YearTable) Average(row YearRow) float64 {
+	months := t.MonthsWithData()
+	if row.Percent || months == 0 {
+		return math.NaN()
+	}
+	return t.Total(row) / float64(months)
+}
+
+// buildYearTable lays out the months as columns with a row per category and
+// the budget calculation underneath
+func buildYearTable(year int, columns []MonthColumn) *YearTable {
+	table := &YearTable{Year: year, columns: columns}
+	for _, column := range columns {
+		table.Months = append(table.Months, column.Figures.Month)
+	}
+
+	categoryRows := func(section string, pick func(MonthColumn) map[string]float64) {
+		names := make(map[string]bool)
+		for _, column := range columns {
+			for name := range pick(column) {
+				names[name] = true
+			}
+		}
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+
+		for _, name := range sorted {
+			row := YearRow{Section: section, Name: name}
+			for _, column := range columns {
+				if column.Empty {
+					row.Values = append(row.Values, math.NaN())
+					continue
+				}
+				row.Values = append(row.Values, pick(column)[name])
+			}
+			table.Rows = append(table.Rows, row)
+		}
+	}
+	categoryRows(sectionFamilyRoot, func(c MonthColumn) map[string]float64 { return c.Family })
+	categoryRows(sectionFamilyLeaf, func(c MonthColumn) map[string]float64 { return c.Leaves })
+	categoryRows(sectionRevenue, func(c MonthColumn) map[string]float64 { return c.Revenue })
+	categoryRows(sectionBusiness, func(c MonthColumn) map[string]float64 { return c.Business })
+
+	budgetRows := []struct {
+		name    string
+		percent bool
+		value   func(MonthColumn) float64
+	}{
+		{"Gross Revenue", false, func(c MonthColumn) float64 { return c.Figures.Revenue }},
+		{"VAT", false, func(c MonthColumn) float64 {
+			revenueExclVAT, _, _ := familyBudgetFrom(c.Figures.Revenue, c.Figures.BusinessExpenses, c.VATRate)
+			return revenueExclVAT - c.Figures.Revenue
+		}},
+		{"Income Tax", false, func(c MonthColumn) float64 {
+			_, incomeTax, _ := familyBudgetFrom(c.Figures.Revenue, c.Figures.BusinessExpenses, c.VATRate)
+			return -incomeTax
+		}},
+		{"Business Expenses", false, func(c MonthColumn) float64 { return c.Figures.BusinessExpenses }},
+		{"Available Family Budget", false, func(c MonthColumn) float64 { return c.Figures.FamilyBudget }},
+		{"Family Spending", false, func(c MonthColumn) float64 { return c.Figures.FamilySpending }},
+		{"Remaining", false, func(c MonthColumn) float64 { return c.Figures.Saved() }},
+		{"Savings Rate", true, func(c MonthColumn) float64 {
+			if rate, ok := c.Figures.SavingsRate(); ok {
+				return rate
+			}
+			return math.NaN()
+		}},
+	}
+	for _, budgetRow := range budgetRows {
+		row := YearRow{Section: sectionBudget, Name: budgetRow.name, Percent: budgetRow.percent}
+		for _, column := range columns {
+			if column.Empty {
+				row.Values = append(row.Values, math.NaN())
+				continue
+			}
+			row.Values = append(row.Values, budgetRow.value(column))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// formatYearValue formats a cell; months without transactions and
+// percentages without a budget are empty, and amounts that round to zero
+// never show as -0.00
+func formatYearValue(v float64, percent bool) string {
+	switch {
+	case math.IsNaN(v):
+		return ""
+	case percent:
+		return fmt.Sprintf("%.1f%%", v*100)
+	case math.Abs(v) < 0.005:
+		return "0.00"
+	default:
+		return fmt.Sprintf("%.2f", v)
+	}
+}
+
+// monthHeader turns YYYY-MM into a short month name
+func monthHeader(month string) string {
+	if t, err := time.Parse("2006-01", month); err == nil {
+		return t.Format("Jan")
+	}
+	return month
+}
+
+// printYearTable writes the overview to the console, a section at a time
+func printYearTable(table *YearTable) {
+	const nameWidth = 28
+	header := fmt.Sprintf("   %-*s", nameWidth, "")
+	for _, month := range table.Months {
+		header += fmt.Sprintf(" %10s", monthHeader(month))
+	}
+	header += fmt.Sprintf(" %11s %10s", "Total", "Average")
+
+	section := ""
+	for _, row := range table.Rows {
+		if row.Section != section {
+			section = row.Section
+			fmt.Printf("\n%s:\n%s\n", section, header)
+		}
+		name := row.Name
+		if len([]rune(name)) > nameWidth {
+			name = string([]rune(name)[:nameWidth-1]) + "…"
+		}
+		line := fmt.Sprintf("   %-*s", nameWidth, name)
+		for _, v := range row.Values {
+			line += fmt.Sprintf(" %10s", formatYearValue(v, row.Percent))
+		}
+		line += fmt.Sprintf(" %11s %10s", formatYearValue(table.Total(row), row.Percent), formatYearValue(table.Average(row), row.Percent))
+		fmt.Println(line)
+	}
+}
+
+// writeYearCSV exports the overview for a spreadsheet
+func writeYearCSV(table *YearTable, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("creating CSV file: %w", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	header := append([]string{"Section", "Category"}, table.Months...)
+	w.Write(append(header, "Total", "Average"))
+	for _, row := range table.Rows {
+		record := []string{row.Section, row.Name}
+		for _, v := range row.Values {
+			record = append(record, formatYearValue(v, row.Percent))
+		}
+		record = append(record, formatYearValue(table.Total(row), row.Percent), formatYearValue(table.Average(row), row.Percent))
+		w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
+	}
+	return nil
+}
+
+// writeYearHTML exports the overview as a standalone HTML page, with the chart
+// when there is one
+func writeYearHTML(table *YearTable, chartFile, filename string) error {
+	var b strings.Builder
+	title := fmt.Sprintf("Yearly overview %d", table.Year)
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", title)
+	b.WriteString("<style>body{font-family:sans-serif}table{border-collapse:collapse}th,td{padding:2px 8px;text-align:right}" +
+		"td:first-child,th:first-child{text-align:left}tr.section th{background:#eee;text-align:left}</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<table>\n<tr><th></th>", title)
+	for _, month := range table.Months {
+		fmt.Fprintf(&b, "<th>%s</th>", monthHeader(month))
+	}
+	b.WriteString("<th>Total</th><th>Average</th></tr>\n")
+
+	section := ""
+	for _, row := range table.Rows {
+		if row.Section != section {
+			section = row.Section
+			fmt.Fprintf(&b, "<tr class=\"section\"><th colspan=\"%d\">%s</th></tr>\n", len(table.Months)+3, html.EscapeString(section))
+		}
+		fmt.Fprintf(&b, "<tr><td>%s</td>", html.EscapeString(row.Name))
+		for _, v := range row.Values {
+			fmt.Fprintf(&b, "<td>%s</td>", formatYearValue(v, row.Percent))
+		}
+		fmt.Fprintf(&b, "<td><b>%s</b></td><td>%s</td></tr>\n", formatYearValue(table.Total(row), row.Percent), formatYearValue(table.Average(row), row.Percent))
+	}
+	b.WriteString("</table>\n")
+	if chartFile != "" {
+		fmt.Fprintf(&b, "<p><img src=\"%s\" alt=\"Family spending by category per month\"></p>\n", html.EscapeString(filepath.Base(chartFile)))
+	}
+	b.WriteString("</body>\n</html>\n")
+
+	if err := os.WriteFile(filename, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("writing HTML file: %w", err)
+	}
+	return nil
+}
+
+// stackedBarSeries is one category of a stacked bar chart: a box per month
+// from the categories below it up to its own amount on top. Unlike the chart
+// library's StackedBarChart the bars keep their amounts instead of being
+// scaled to 100%.
+type stackedBarSeries struct {
+	Name    string
+	Style   chart.Style
+	Bottoms []float64 // by month, x = index + 1
+	Tops    []float64
+}
+
+func (s stackedBarSeries) GetName() string           { return s.Name }
+func (s stackedBarSeries) GetYAxis() chart.YAxisType { return chart.YAxisPrimary }
+func (s stackedBarSeries) GetStyle() chart.Style     { return s.Style }
+func (s stackedBarSeries) Validate() error           { return nil }
+func (s stackedBarSeries) Len() int                  { return len(s.Tops) }
+
+// GetBoundedValues gives the chart the extent of a month's box for its ranges
+func (s stackedBarSeries) GetBoundedValues(index int) (x, y1, y2 float64) {
+	return float64(index + 1), s.Bottoms[index], s.Tops[index]
+}
+
+// Render draws the boxes, 70% of the space per month wide
+func (s stackedBarSeries) Render(r chart.Renderer, canvasBox chart.Box, xrange, yrange chart.Range, defaults chart.Style) {
+	halfWidth := int(float64(xrange.Translate(1)-xrange.Translate(0)) * 0.35)
+	style := s.Style.InheritFrom(defaults)
+	for i := range s.Tops {
+		if s.Tops[i] <= s.Bottoms[i] {
+			continue
+		}
+		x := canvasBox.Left + xrange.Translate(float64(i+1))
+		chart.Draw.Box(r, chart.Box{
+			Top:    canvasBox.Bottom - yrange.Translate(s.Tops[i]),
+			Left:   x - halfWidth,
+			Right:  x + halfWidth,
+			Bottom: canvasBox.Bottom - yrange.Translate(s.Bottoms[i]),
+		}, style)
+	}
+}
+
+// renderYearChart draws the family spending per month as bars stacked by
+// root category, in euros. Each category keeps its color across the months
+// and months without transactions have no bar.
+func renderYearChart(table *YearTable, filename string) error {
+	var categories []string
+	for _, row := range table.Rows {
+		if row.Section == sectionFamilyRoot {
+			categories = append(categories, row.Name)
+		}
+	}
+
+	stacked := make([]float64, len(table.columns)) // spending charted so far per month
+	var series []chart.Series
+	var spent bool
+	for j, category := range categories {
+		color := chartColors[j%len(chartColors)]
+		s := stackedBarSeries{
+			Name:  category,
+			Style: chart.Style{FillColor: color, StrokeColor: color, StrokeWidth: 1},
+		}
+		for i, column := range table.columns {
+			amount := 0.0
+			if !column.Empty {
+				amount = math.Max(-column.Family[category], 0)
+			}
+			s.Bottoms = append(s.Bottoms, stacked[i])
+			stacked[i] += amount
+			s.Tops = append(s.Tops, stacked[i])
+			spent = spent || amount > 0
+		}
+		series = append(series, s)
+	}
+	if !spent {
+		return fmt.Errorf("no family spending to chart")
+	}
+
+	graph := chart.Chart{
+		Title:      fmt.Sprintf("Family spending by category %d", table.Year),
+		Width:      1000,
+		Height:     600,
+		Background: chart.Style{Padding: chart.Box{Top: 50, Left: 20, Right: 20, Bottom: 20}},
+		YAxis: chart.YAxis{ValueFormatter: func(v interface{}) string {
+			return fmt.Sprintf("€%.0f", v.(float64))
+		}},
+		Series: series,
+	}
+	// A tick per month, and unlabeled ones around them so the outer bars fit
+	graph.XAxis.Ticks = append(graph.XAxis.Ticks, chart.Tick{Value: 0})
+	for i, month := range table.Months {
+		graph.XAxis.Ticks = append(graph.XAxis.Ticks, chart.Tick{Value: float64(i + 1), Label: monthHeader(month)})
+	}
+	graph.XAxis.Ticks = append(graph.XAxis.Ticks, chart.Tick{Value: float64(len(table.Months) + 1)})
+	graph.Elements = []chart.Renderable{chart.LegendLeft(&graph)}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("creating chart file: %w", err)
+	}
+	defer file.Close()
+	if err := graph.Render(chart.PNG, file); err != nil {
+		return fmt.Errorf("rendering chart: %w", err)
+	}
+	return nil
+}
+
+// yearPreview returns the preview the report applies to a month: without
+// internal transfers, with the splits and categorization rules booked. Split
+// or rule files that fail to load are left out with a warning.
+func yearPreview(accounts []LedgerAccount, splitsFile, rulesFile string) func([]FinancialMutation) []FinancialMutation {
+	splits, err := loadSplits(splitsFile, accounts)
+	if err != nil {
+		fmt.Printf("Warning: splits not previewed: %v\n", err)
+	}
+	rules, err := loadCategorizationRules(rulesFile, accounts)
+	if err != nil {
+		fmt.Printf("Warning: categorization rules not previewed: %v\n", err)
+	}
+	transferConfig := transferConfigFromEnv()
+	return func(mutations []FinancialMutation) []FinancialMutation {
+		return previewBookings(mutations, transferConfig, splits, rules)
+	}
+}
+
+// runYear builds the yearly overview from the store, or from the monthly
+// snapshots in a directory, and exports it
+func runYear(args []string) {
+	fs := flag.NewFlagSet("year", flag.ExitOnError)
+	storeFile := fs.String("store", defaultStoreFile, "Local store of synced Moneybird data")
+	snapshotDir := fs.String("snapshots", "", "Build the overview from the financial_data_YYYY-MM.json snapshots in this directory instead of the store")
+	year := fs.Int("year", time.Now().Year(), "Year to report")
+	csvFile := fs.String("csv", "", "CSV file to write (default: year_YYYY.csv)")
+	htmlFile := fs.String("html", "", "HTML file to write (default: year_YYYY.html)")
+	chartFile := fs.String("chart", "", "Chart file to write (default: year_YYYY.png)")
+	ratesFile := fs.String("exchange-rates", defaultExchangeRatesFile, "File with exchange rates to euros, for foreign amounts without a base amount")
+	splitsFile := fs.String("splits", defaultSplitsFile, "File with split rules and overrides, previewed like in the report")
+	rulesFile := fs.String("rules", defaultRulesFile, "File with categorization rules, previewed like in the report")
+	fs.Parse(args)
+
+	if *csvFile == "" {
+		*csvFile = fmt.Sprintf("year_%d.csv", *year)
+	}
+	if *htmlFile == "" {
+		*htmlFile = fmt.Sprintf("year_%d.html", *year)
+	}
+	if *chartFile == "" {
+		*chartFile = fmt.Sprintf("year_%d.png", *year)
+	}
+
+	var store *Store
+	var exchangeRates map[string]float64
+	var preview func([]FinancialMutation) []FinancialMutation
+	if *snapshotDir == "" {
+		var err error
+		if store, err = OpenStore(*storeFile); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if exchangeRates, err = loadExchangeRates(*ratesFile); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		preview = yearPreview(store.Accounts(), *splitsFile, *rulesFile)
+	}
+
+	// Every month of the year gets a column, up to the current month
+	var columns []MonthColumn
+	for m := time.January; m <= time.December; m++ {
+		month := time.Date(*year, m, 1, 0, 0, 0, 0, time.UTC)
+		if month.After(time.Now()) {
+			break
+		}
+		empty := MonthColumn{Figures: MonthFigures{Month: month.Format("2006-01")}, Empty: true}
+
+		// Store months go through the same path as the report's trailing
+		// months: previewed, and with the revenue the month was reported with
+		if store != nil {
+			column, ok := monthColumn(store, month, exchangeRates, preview)
+			if !ok {
+				column = empty
+			}
+			columns = append(columns, column)
+			continue
+		}
+
+		filename := filepath.Join(*snapshotDir, snapshotFilename(month))
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			columns = append(columns, empty)
+			continue
+		}
+		snap, err := loadSnapshot(filename)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(snap.Mutations) == 0 {
+			columns = append(columns, empty)
+			continue
+		}
+		if err := addExchangeRates(snap, *ratesFile); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if preview == nil {
+			preview = yearPreview(snap.LedgerAccounts, *splitsFile, *rulesFile)
+		}
+		snap.Mutations = preview(snap.Mutations)
+		columns = append(columns, summarizeMonth(snap, month.Format("2006-01")))
+	}
+	table := buildYearTable(*year, columns)
+	if table.MonthsWithData() == 0 {
+		fmt.Printf("No transactions in %d\n", *year)
+		return
+	}
+	fmt.Printf("Yearly overview %d (%d of %d months with transactions):\n", *year, table.MonthsWithData(), len(columns))
+	printYearTable(table)
+	fmt.Println()
+
+	if err := renderYearChart(table, *chartFile); err != nil {
+		fmt.Printf("No chart: %v\n", err)
+		*chartFile = ""
+	} else {
+		fmt.Printf("✓ Chart saved to %s\n", *chartFile)
+	}
+	if err := writeYearCSV(table, *csvFile); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	} else {
+		fmt.Printf("✓ CSV saved to %s\n", *csvFile)
+	}
+	if err := writeYearHTML(table, *chartFile, *htmlFile); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	} else {
+		fmt.Printf("✓ HTML saved to %s\n", *htmlFile)
+	}
+}
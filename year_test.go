@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestYearTableKeepsEmptyMonths(t *testing.T) {
+	columns := []MonthColumn{
+		{Figures: MonthFigures{Month: "2025-01"}, Empty: true},
+		{
+			Figures: MonthFigures{Month: "2025-02", Revenue: 1210, FamilyBudget: 700, FamilySpending: -300},
+			Family:  map[string]float64{"Huishouden": -200, "Vervoer": -100},
+			Leaves:  map[string]float64{"Boodschappen": -200, "Benzine": -100},
+		},
+		{
+			Figures: MonthFigures{Month: "2025-03", Revenue: 1210, FamilyBudget: 700, FamilySpending: -100},
+			Family:  map[string]float64{"Huishouden": -100},
+			Leaves:  map[string]float64{"Boodschappen": -100},
+		},
+	}
+	table := buildYearTable(2025, columns)
+	if len(table.Months) != 3 || table.MonthsWithData() != 2 {
+		t.Fatalf("months = %v with %d with data, want three of which two with data", table.Months, table.MonthsWithData())
+	}
+
+	for _, row := range table.Rows {
+		if !math.IsNaN(row.Values[0]) {
+			t.Errorf("%s: January = %v, want an empty cell", row.Name, row.Values[0])
+		}
+		if row.Name == "Vervoer" {
+			// A month with data but nothing in the category counts for the average
+			if row.Values[2] != 0 || table.Total(row) != -100 || table.Average(row) != -50 {
+				t.Errorf("Vervoer = %v, total %.2f, average %.2f, want an average over both months", row.Values, table.Total(row), table.Average(row))
+			}
+		}
+	}
+}
+
+func TestYearChartStacksAmounts(t *testing.T) {
+	columns := []MonthColumn{
+		{Figures: MonthFigures{Month: "2025-01"}, Empty: true},
+		{Figures: MonthFigures{Month: "2025-02"}, Family: map[string]float64{"Huishouden": -200, "Vervoer": -100}},
+		{Figures: MonthFigures{Month: "2025-03"}, Family: map[string]float64{"Huishouden": -100}},
+	}
+	table := buildYearTable(2025, columns)
+	if err := renderYearChart(table, filepath.Join(t.TempDir(), "year.png")); err != nil {
+		t.Fatalf("renderYearChart: %v", err)
+	}
+
+	if err := renderYearChart(buildYearTable(2025, columns[:1]), filepath.Join(t.TempDir(), "empty.png")); err == nil {
+		t.Error("charted a year without spending")
+	}
+}
+
+func TestYearStoreColumnsArePreviewed(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStore(filepath.Join(dir, "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.LedgerAccounts["omzet"] = LedgerAccount{ID: "omzet", Name: "Omzet", AccountType: "revenue"}
+	store.LedgerAccounts["food"] = LedgerAccount{ID: "food", Name: "Boodschappen", AccountType: "equity"}
+	store.FinancialMutations["m1"] = FinancialMutation{ID: "m1", Date: "2025-03-05", Amount: "-80.00",
+		State: "unprocessed", ContraAccountName: "Jumbo Utrecht"}
+	store.Revenue["2025-03"] = 1210
+
+	rulesFile := filepath.Join(dir, "rules.json")
+	rules := `{"rules": [{"name": "jumbo", "contra_name": "jumbo", "ledger_account": "Boodschappen"}]}`
+	if err := os.WriteFile(rulesFile, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	preview := yearPreview(store.Accounts(), filepath.Join(dir, "no-splits.json"), rulesFile)
+	column, ok := monthColumn(store, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), nil, preview)
+	if !ok {
+		t.Fatal("March has no column")
+	}
+	if column.Leaves["Boodschappen"] != -80 {
+		t.Errorf("Boodschappen = %.2f, want -80.00 from the rule preview", column.Leaves["Boodschappen"])
+	}
+	if column.Figures.Revenue != 1210 {
+		t.Errorf("revenue = %.2f, want the reported 1210.00", column.Figures.Revenue)
+	}
+}